@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"compiler/token"
+	"math/big"
 	"strings"
 )
 
@@ -15,11 +16,14 @@ import (
 	tokenliteral interface method is used for debugging the program: makes easy to print out strings.
 	Prefix statement should be written , For eg Prefix statement AST parsing is 5 * 5 where * is prefix for parsing AST
 	String method for debug spits out string concat buffer out for debugging.
+	Pos method returns the source position of the token the node was created from, For infix expressions this is
+	the operator, for calls the opening paren and for index expressions the opening bracket.
 */
 
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -64,6 +68,18 @@ type IntegerLiteral struct {
 	Value int64
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+// Big integer literal `123n`, The token literal keeps the n suffix
+type BigIntLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+// Let statement declares a name in the enclosing block, The token is either let or const
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
@@ -92,10 +108,29 @@ type ExpressionStatement struct {
 	Expression Expression
 }
 
+// Name is set when the function literal is bound with let, It is used for recursion and error messages.
+// Arrow functions have the arrow as token and an expression body is wrapped into a block.
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string
+}
+
+// Function declaration `function name(params) { body }` binds the function in the enclosing block, It is
+// hoisted so the function can be called before the declaration.
+type FunctionDeclaration struct {
+	Token    token.Token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+// Macro literal `macro(params) { body }`, Macros are bound with top level let statements and are expanded
+// before the program runs. The arguments of a macro call are passed to the body unevaluated as quotes.
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
 }
 
 type CallExpression struct {
@@ -114,6 +149,8 @@ type ArrayLiteral struct {
 	Elements []Expression
 }
 
+// Index expression is also used for member access `left.name`, The token is then the dot and the index is the
+// name as string literal.
 type IndexExpression struct {
 	Token token.Token
 	Left  Expression
@@ -125,6 +162,87 @@ type HashLiteral struct {
 	Pairs map[Expression]Expression
 }
 
+// Assignment stores the value into the target, The target is an identifier or an index expression. The
+// operator is "=" or one of the compound operators like "+=" and the value of the expression is the new value.
+type AssignExpression struct {
+	Token    token.Token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+// Increment and decrement of the target with "++" and "--", Prefix updates return back the new value and
+// postfix updates return back the old one.
+type UpdateExpression struct {
+	Token    token.Token
+	Operator string
+	Target   Expression
+	Prefix   bool
+}
+
+// While loop runs the body as long as the condition is truthy
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+// C style for loop, Every part of the header is optional and the update is a statement
+type ForStatement struct {
+	Token     token.Token
+	Init      Statement
+	Condition Expression
+	Update    Statement
+	Body      *BlockStatement
+}
+
+// Loops over the elements of an array, a string or a hash with `for (x of value)` and over the keys or
+// indexes with `for (x in value)`, The operator is either "of" or "in". Every run of the body gets a new
+// binding of the variable which is constant when declared with `for (const x of value)`.
+type ForEachStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Constant bool
+	Operator string
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+type BreakStatement struct {
+	Token token.Token
+}
+
+type ContinueStatement struct {
+	Token token.Token
+}
+
+// Throws the value as exception, It is caught by the closest try statement around it
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+// Runs the block and the catch block when the block throws, The parameter of the catch block is optional.
+// The finally block runs after the block and the catch block however they were left. At least one of the
+// catch and the finally block is there.
+type TryStatement struct {
+	Token   token.Token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+// Bad expressions and statements stand in for source that could not be parsed, The token is where the broken
+// source starts. They only appear in programs that have parser errors and keep the rest of the tree usable.
+type BadExpression struct {
+	Token token.Token
+}
+
+type BadStatement struct {
+	Token token.Token
+}
+
 func (p *Program) TokenLiteral() string {
 	if len(p.Statements) > 0 {
 		return p.Statements[0].TokenLiteral()
@@ -133,6 +251,13 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var bytes bytes.Buffer
 	for _, buffer := range p.Statements {
@@ -141,8 +266,26 @@ func (p *Program) String() string {
 	return bytes.String()
 }
 
+// Only expression and return statements leave a value for the program, The virtual machine would otherwise
+// report back the last value popped by an earlier statement
+func (p *Program) EndsWithValue() bool {
+	if len(p.Statements) == 0 {
+		return false
+	}
+	switch p.Statements[len(p.Statements)-1].(type) {
+	case *ExpressionStatement, *ReturnStatement:
+		return true
+	}
+	return false
+}
+
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
+
+// Constants are declared with const and can not be assigned to
+func (ls *LetStatement) IsConst() bool { return ls.Token.Type == token.CONST }
+
 func (ls *LetStatement) String() string {
 	var bytes bytes.Buffer
 	bytes.WriteString(ls.TokenLiteral() + " ")
@@ -156,15 +299,17 @@ func (ls *LetStatement) String() string {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 func (i *Identifier) String() string       { return i.Value }
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
 func (rs *ReturnStatement) String() string {
 	var bytes bytes.Buffer
 	bytes.WriteString(rs.TokenLiteral() + " ")
 	if rs.ReturnValue != nil {
-		bytes.WriteString(rs.TokenLiteral())
+		bytes.WriteString(rs.ReturnValue.String())
 	}
 	bytes.WriteString(";")
 	return bytes.String()
@@ -172,6 +317,7 @@ func (rs *ReturnStatement) String() string {
 
 func (e *ExpressionStatement) statementNode()       {}
 func (e *ExpressionStatement) TokenLiteral() string { return e.Token.Literal }
+func (e *ExpressionStatement) Pos() token.Position  { return e.Token.Pos }
 func (e *ExpressionStatement) String() string {
 	if e.Expression != nil {
 		return e.Expression.String()
@@ -181,10 +327,22 @@ func (e *ExpressionStatement) String() string {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
+func (bl *BigIntLiteral) expressionNode()      {}
+func (bl *BigIntLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntLiteral) Pos() token.Position  { return bl.Token.Pos }
+func (bl *BigIntLiteral) String() string       { return bl.Token.Literal }
+
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var bytesOut bytes.Buffer
 	bytesOut.WriteString("(")
@@ -196,6 +354,7 @@ func (pe *PrefixExpression) String() string {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *InfixExpression) String() string {
 	var bytesOut bytes.Buffer
 	bytesOut.WriteString("(")
@@ -208,10 +367,12 @@ func (ie *InfixExpression) String() string {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -227,6 +388,7 @@ func (ie *IfExpression) String() string {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	for _, s := range bs.Statements {
@@ -237,12 +399,20 @@ func (bs *BlockStatement) String() string {
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	params := []string{}
 	for _, p := range fl.Parameters {
 		params = append(params, p.String())
 	}
+	if fl.Token.Type == token.ARROW {
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(") => ")
+		out.WriteString(fl.Body.String())
+		return out.String()
+	}
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
@@ -251,8 +421,44 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+	return out.String()
+}
+
+func (fd *FunctionDeclaration) statementNode()       {}
+func (fd *FunctionDeclaration) TokenLiteral() string { return fd.Token.Literal }
+func (fd *FunctionDeclaration) Pos() token.Position  { return fd.Token.Pos }
+func (fd *FunctionDeclaration) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range fd.Function.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(fd.TokenLiteral() + " ")
+	out.WriteString(fd.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fd.Function.Body.String())
+	return out.String()
+}
+
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Pos }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
@@ -268,10 +474,12 @@ func (ce *CallExpression) String() string {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 	elements := []string{}
@@ -286,18 +494,46 @@ func (al *ArrayLiteral) String() string {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Pos }
+
+// Member access is written with a dot instead of brackets
+func (ie *IndexExpression) IsMember() bool { return ie.Token.Type == token.DOT }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.IsMember() {
+		out.WriteString(".")
+		out.WriteString(ie.Index.String())
+		out.WriteString(")")
+		return out.String()
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 	return out.String()
 }
 
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Token.Pos }
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " " + ae.Operator + " " + ae.Value.String() + ")"
+}
+
+func (ue *UpdateExpression) expressionNode()      {}
+func (ue *UpdateExpression) TokenLiteral() string { return ue.Token.Literal }
+func (ue *UpdateExpression) Pos() token.Position  { return ue.Token.Pos }
+func (ue *UpdateExpression) String() string {
+	if ue.Prefix {
+		return "(" + ue.Operator + ue.Target.String() + ")"
+	}
+	return "(" + ue.Target.String() + ue.Operator + ")"
+}
+
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
@@ -309,3 +545,87 @@ func (hl *HashLiteral) String() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpression) Pos() token.Position  { return be.Token.Pos }
+func (be *BadExpression) String() string       { return "<bad expression>" }
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BadStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BadStatement) String() string       { return "<bad statement>" }
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	return "while" + ws.Condition.String() + " " + ws.Body.String()
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fs.Init != nil {
+		out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Update != nil {
+		out.WriteString(strings.TrimSuffix(fs.Update.String(), ";"))
+	}
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+func (fe *ForEachStatement) statementNode()       {}
+func (fe *ForEachStatement) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForEachStatement) Pos() token.Position  { return fe.Token.Pos }
+func (fe *ForEachStatement) String() string {
+	declaration := ""
+	if fe.Constant {
+		declaration = "const "
+	}
+	return "for (" + declaration + fe.Variable.String() + " " + fe.Operator + " " + fe.Iterable.String() + ") " + fe.Body.String()
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return "break;" }
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return "continue;" }
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) String() string       { return "throw " + ts.Value.String() + ";" }
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try {" + ts.Block.String() + "}")
+	if ts.Catch != nil {
+		out.WriteString(" catch ")
+		if ts.Param != nil {
+			out.WriteString("(" + ts.Param.String() + ") ")
+		}
+		out.WriteString("{" + ts.Catch.String() + "}")
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally {" + ts.Finally.String() + "}")
+	}
+	return out.String()
+}
@@ -26,3 +26,99 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%T", program.String())
 	}
 }
+
+func TestEndsWithValue(t *testing.T) {
+	tests := []struct {
+		statements []Statement
+		expected   bool
+	}{
+		{[]Statement{}, false},
+		{[]Statement{&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}}}, true},
+		{[]Statement{&ReturnStatement{ReturnValue: &IntegerLiteral{Value: 1}}}, true},
+		{[]Statement{&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}}, &LetStatement{}}, false},
+	}
+	for _, tt := range tests {
+		program := &Program{Statements: tt.statements}
+		if program.EndsWithValue() != tt.expected {
+			t.Errorf("EndsWithValue() of %d statements wrong. want=%t", len(tt.statements), tt.expected)
+		}
+	}
+}
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return &IntegerLiteral{Value: 2}
+	}
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}}, &Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		{&InfixExpression{Left: one(), Operator: "+", Right: two()}, &InfixExpression{Left: two(), Operator: "+", Right: two()}},
+		{&PrefixExpression{Operator: "-", Right: one()}, &PrefixExpression{Operator: "-", Right: two()}},
+		{&IndexExpression{Left: one(), Index: one()}, &IndexExpression{Left: two(), Index: two()}},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&LetStatement{Name: &Identifier{Value: "x"}, Value: one()}, &LetStatement{Name: &Identifier{Value: "x"}, Value: two()}},
+		{
+			&FunctionLiteral{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}},
+			&FunctionLiteral{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		},
+		{&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one()}}, &CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two()}}},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{&AssignExpression{Target: &Identifier{Value: "x"}, Operator: "+=", Value: one()}, &AssignExpression{Target: &Identifier{Value: "x"}, Operator: "+=", Value: two()}},
+		{
+			&WhileStatement{Condition: one(), Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}},
+			&WhileStatement{Condition: two(), Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		},
+		{&ThrowStatement{Value: one()}, &ThrowStatement{Value: two()}},
+		{
+			&TryStatement{
+				Block:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Catch:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&TryStatement{
+				Block:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Catch:   &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Finally: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		before := tt.input.String()
+		modified := Modify(tt.input, turnOneIntoTwo)
+		if modified.String() != tt.expected.String() {
+			t.Errorf("not equal. got=%q, want=%q", modified.String(), tt.expected.String())
+		}
+		if _, ok := tt.input.(*IntegerLiteral); !ok && tt.input.String() != before {
+			t.Errorf("input was changed. got=%q, want=%q", tt.input.String(), before)
+		}
+	}
+
+	hash := &HashLiteral{Pairs: map[Expression]Expression{one(): one()}}
+	modified := Modify(hash, turnOneIntoTwo).(*HashLiteral)
+	for key, value := range modified.Pairs {
+		if key.(*IntegerLiteral).Value != 2 || value.(*IntegerLiteral).Value != 2 {
+			t.Errorf("hash pair not modified. got=%v: %v", key, value)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package ast
+
+// Modifier is called with every node of the tree after its children were modified, The returned node
+// replaces the node in the tree.
+type ModifierFunc func(Node) Node
+
+// Walks the tree depth first and replaces every node with what the modifier returns back for it, Children are
+// modified before their parent so the modifier sees the already modified children. Nodes are copied before
+// their children are replaced, So the tree that was passed in is left unchanged and can be modified again.
+// A child replaced by a node of the wrong kind, like a statement where an expression is expected, is kept.
+// Used by quote and by macro expansion.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		copied := *node
+		copied.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&copied)
+	case *ExpressionStatement:
+		copied := *node
+		copied.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&copied)
+	case *InfixExpression:
+		copied := *node
+		copied.Left = modifyExpression(node.Left, modifier)
+		copied.Right = modifyExpression(node.Right, modifier)
+		return modifier(&copied)
+	case *PrefixExpression:
+		copied := *node
+		copied.Right = modifyExpression(node.Right, modifier)
+		return modifier(&copied)
+	case *IndexExpression:
+		copied := *node
+		copied.Left = modifyExpression(node.Left, modifier)
+		copied.Index = modifyExpression(node.Index, modifier)
+		return modifier(&copied)
+	case *IfExpression:
+		copied := *node
+		copied.Condition = modifyExpression(node.Condition, modifier)
+		copied.Consequence = modifyBlock(node.Consequence, modifier)
+		if node.Alternative != nil {
+			copied.Alternative = modifyBlock(node.Alternative, modifier)
+		}
+		return modifier(&copied)
+	case *BlockStatement:
+		copied := *node
+		copied.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&copied)
+	case *ReturnStatement:
+		copied := *node
+		copied.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&copied)
+	case *LetStatement:
+		copied := *node
+		copied.Value = modifyExpression(node.Value, modifier)
+		return modifier(&copied)
+	case *FunctionLiteral:
+		copied := *node
+		copied.Body = modifyBlock(node.Body, modifier)
+		return modifier(&copied)
+	case *FunctionDeclaration:
+		copied := *node
+		if fn, ok := Modify(node.Function, modifier).(*FunctionLiteral); ok {
+			copied.Function = fn
+		}
+		return modifier(&copied)
+	case *MacroLiteral:
+		copied := *node
+		copied.Body = modifyBlock(node.Body, modifier)
+		return modifier(&copied)
+	case *CallExpression:
+		copied := *node
+		copied.Function = modifyExpression(node.Function, modifier)
+		copied.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&copied)
+	case *ArrayLiteral:
+		copied := *node
+		copied.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&copied)
+	case *HashLiteral:
+		copied := *node
+		copied.Pairs = make(map[Expression]Expression)
+		for key, value := range node.Pairs {
+			copied.Pairs[modifyExpression(key, modifier)] = modifyExpression(value, modifier)
+		}
+		return modifier(&copied)
+	case *AssignExpression:
+		copied := *node
+		copied.Target = modifyExpression(node.Target, modifier)
+		copied.Value = modifyExpression(node.Value, modifier)
+		return modifier(&copied)
+	case *UpdateExpression:
+		copied := *node
+		copied.Target = modifyExpression(node.Target, modifier)
+		return modifier(&copied)
+	case *WhileStatement:
+		copied := *node
+		copied.Condition = modifyExpression(node.Condition, modifier)
+		copied.Body = modifyBlock(node.Body, modifier)
+		return modifier(&copied)
+	case *ForStatement:
+		copied := *node
+		if node.Init != nil {
+			copied.Init = modifyStatement(node.Init, modifier)
+		}
+		if node.Condition != nil {
+			copied.Condition = modifyExpression(node.Condition, modifier)
+		}
+		if node.Update != nil {
+			copied.Update = modifyStatement(node.Update, modifier)
+		}
+		copied.Body = modifyBlock(node.Body, modifier)
+		return modifier(&copied)
+	case *ForEachStatement:
+		copied := *node
+		copied.Iterable = modifyExpression(node.Iterable, modifier)
+		copied.Body = modifyBlock(node.Body, modifier)
+		return modifier(&copied)
+	case *ThrowStatement:
+		copied := *node
+		copied.Value = modifyExpression(node.Value, modifier)
+		return modifier(&copied)
+	case *TryStatement:
+		copied := *node
+		copied.Block = modifyBlock(node.Block, modifier)
+		if node.Catch != nil {
+			copied.Catch = modifyBlock(node.Catch, modifier)
+		}
+		if node.Finally != nil {
+			copied.Finally = modifyBlock(node.Finally, modifier)
+		}
+		return modifier(&copied)
+	}
+	return modifier(node)
+}
+
+func modifyExpression(expression Expression, modifier ModifierFunc) Expression {
+	if modified, ok := Modify(expression, modifier).(Expression); ok {
+		return modified
+	}
+	return expression
+}
+
+func modifyStatement(statement Statement, modifier ModifierFunc) Statement {
+	if modified, ok := Modify(statement, modifier).(Statement); ok {
+		return modified
+	}
+	return statement
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
+
+func modifyExpressions(expressions []Expression, modifier ModifierFunc) []Expression {
+	modified := make([]Expression, len(expressions))
+	for i, expression := range expressions {
+		modified[i] = modifyExpression(expression, modifier)
+	}
+	return modified
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	modified := make([]Statement, len(statements))
+	for i, statement := range statements {
+		modified[i] = modifyStatement(statement, modifier)
+	}
+	return modified
+}
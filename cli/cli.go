@@ -0,0 +1,216 @@
+// This package contains the command line interface for BJS, Every subcommand is registered in the
+// commands table and gets its own flag set, so that help text and exit codes stay consistent.
+package cli
+
+import (
+	"compiler/constants"
+	"compiler/object"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exit codes returned back by Run, Usage errors are kept separate from program failures so that
+// scripts and CI can tell a typo in the command line apart from a failing BJS program.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+// Engines which can execute a parsed program
+const (
+	EngineEval = "eval"
+	EngineVM   = "vm"
+)
+
+// Streams used by the commands, Kept in a struct so that tests can run commands against buffers.
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(s *streams, args []string) int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"run", "run [--engine=eval|vm] [--overflow=wrap|error|promote] [-e source | file.bjs] [args...]", "Run a BJS script", runCommand},
+		{"repl", "repl [--engine=eval|vm] [--overflow=wrap|error|promote]", "Start the interactive REPL", replCommand},
+		{"compile", "compile [-e source | file.bjs]", "Compile a script to bytecode and report the result", compileCommand},
+		{"disasm", "disasm [-e source | file.bjs]", "Print the bytecode instructions and constants of a script", disasmCommand},
+		{"tokens", "tokens [-e source | file.bjs]", "Print the tokens produced by the lexer", tokensCommand},
+		{"ast", "ast [-e source | file.bjs]", "Print the parsed program after macro expansion, one statement per line", astCommand},
+		{"version", "version", "Print the BJS version", versionCommand},
+		{"help", "help [command]", "Show help for BJS or for a single command", helpCommand},
+	}
+}
+
+// Run executes the command line given in args (without the binary name) and returns back the exit code.
+// Running without arguments starts the REPL and running a .bjs file directly is a shorthand for `bjs run`.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	s := &streams{stdin: stdin, stdout: stdout, stderr: stderr}
+	// Programs print through the builtins, so their output has to follow the stdout of the command
+	object.Stdout = stdout
+	if len(args) == 0 {
+		return replCommand(s, args)
+	}
+	name := args[0]
+	if filepath.Ext(name) == ".bjs" {
+		return runCommand(s, args)
+	}
+	if name == "-h" || name == "--help" {
+		printUsage(stdout)
+		return ExitOK
+	}
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(stderr, "bjs: unknown command %q\n\n", name)
+		printUsage(stderr)
+		return ExitUsage
+	}
+	return cmd.run(s, args[1:])
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintf(out, "BJS %s - JavaScript For Servers, Blazingly Fast and Compiled\n\n", constants.VERSION)
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  bjs <command> [flags] [arguments]")
+	fmt.Fprintln(out, "  bjs file.bjs [args...]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Run `bjs help <command>` for the flags of a command.")
+}
+
+// Creates the flag set for a command, Parse errors are reported on stderr together with the command usage.
+func newFlagSet(s *streams, name string) *flag.FlagSet {
+	cmd := lookupCommand(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(s.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bjs %s\n\n%s\n", cmd.usage, cmd.summary)
+		var hasFlags bool
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// Parses the flags of a command and returns back the exit code to use when parsing did not succeed.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return ExitOK, false
+	}
+	if err != nil {
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+func engineFlag(fs *flag.FlagSet) *string {
+	return fs.String("engine", EngineEval, "execution engine to use: eval (tree walking interpreter) or vm (compiler and virtual machine)")
+}
+
+func validEngine(s *streams, engine string) bool {
+	if engine == EngineEval || engine == EngineVM {
+		return true
+	}
+	fmt.Fprintf(s.stderr, "bjs: unknown engine %q, expected %s or %s\n", engine, EngineEval, EngineVM)
+	return false
+}
+
+func overflowFlag(fs *flag.FlagSet) *string {
+	return fs.String("overflow", string(object.OverflowWrap), "what integer arithmetic does when a result does not fit into 64 bits: wrap, error or promote (to big integers)")
+}
+
+// Sets the overflow policy of both engines, Reports back false when the policy is unknown
+func setOverflow(s *streams, name string) bool {
+	policy, ok := object.ParseOverflowPolicy(name)
+	if !ok {
+		fmt.Fprintf(s.stderr, "bjs: unknown overflow policy %q, expected %s, %s or %s\n", name, object.OverflowWrap, object.OverflowError, object.OverflowPromote)
+		return false
+	}
+	object.Overflow = policy
+	return true
+}
+
+// Returns back the name and content of the source given either inline through -e or as the first
+// positional argument, The remaining positional arguments are returned back as well.
+func readSource(s *streams, fs *flag.FlagSet, inline string) (string, string, []string, int) {
+	if inline != "" {
+		return "<inline>", inline, fs.Args(), ExitOK
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintf(s.stderr, "bjs %s: missing script file\n", fs.Name())
+		fs.Usage()
+		return "", "", nil, ExitUsage
+	}
+	filename := fs.Arg(0)
+	if filepath.Ext(filename) != ".bjs" {
+		fmt.Fprintf(s.stderr, "%s: file extension is wrong, make sure you are using bjs file extension.\n", filename)
+		return "", "", nil, ExitUsage
+	}
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "bjs %s: %s\n", fs.Name(), err)
+		return "", "", nil, ExitFailure
+	}
+	return filename, string(source), fs.Args()[1:], ExitOK
+}
+
+func helpCommand(s *streams, args []string) int {
+	if len(args) == 0 {
+		printUsage(s.stdout)
+		return ExitOK
+	}
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(s.stderr, "bjs help: unknown command %q\n", args[0])
+		return ExitUsage
+	}
+	// Running the command with -h registers its flags and prints the usage.
+	return cmd.run(&streams{stdin: s.stdin, stdout: s.stdout, stderr: s.stdout}, []string{"-h"})
+}
+
+func versionCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "version")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	fmt.Fprintf(s.stdout, "bjs version %s\n", constants.VERSION)
+	return ExitOK
+}
+
+func printLines(out io.Writer, lines []string) {
+	io.WriteString(out, strings.Join(lines, "\n"))
+	if len(lines) > 0 {
+		io.WriteString(out, "\n")
+	}
+}
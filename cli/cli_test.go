@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"version"}, ExitOK},
+		{[]string{"help"}, ExitOK},
+		{[]string{"help", "run"}, ExitOK},
+		{[]string{"run", "-e", "1 + 2"}, ExitOK},
+		{[]string{"run", "--engine=vm", "-e", "1 + 2"}, ExitOK},
+		{[]string{"run", "-e", "let x = ;"}, ExitFailure},
+		{[]string{"run", "-e", "foobar"}, ExitFailure},
+		{[]string{"run", "--engine=vm", "-e", "-true"}, ExitFailure},
+		{[]string{"run", "--engine=jit", "-e", "1"}, ExitUsage},
+		{[]string{"run", "-e", "1 / 0"}, ExitFailure},
+		{[]string{"run", "--overflow=error", "-e", "9223372036854775807 + 1"}, ExitFailure},
+		{[]string{"run", "--overflow=promote", "--engine=vm", "-e", "9223372036854775807 + 1"}, ExitOK},
+		{[]string{"run", "--overflow=wrap", "-e", "9223372036854775807 + 1"}, ExitOK},
+		{[]string{"run", "--overflow=saturate", "-e", "1"}, ExitUsage},
+		{[]string{"run"}, ExitUsage},
+		{[]string{"run", "script.js"}, ExitUsage},
+		{[]string{"run", "missing.bjs"}, ExitFailure},
+		{[]string{"run", "--no-such-flag"}, ExitUsage},
+		{[]string{"unknown"}, ExitUsage},
+		{[]string{"help", "unknown"}, ExitUsage},
+	}
+	for _, tt := range tests {
+		code, _, stderr := runCLI(tt.args...)
+		if code != tt.expected {
+			t.Errorf("bjs %s: wrong exit code. want=%d, got=%d (stderr=%q)", strings.Join(tt.args, " "), tt.expected, code, stderr)
+		}
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"version"}, "bjs version 0.1.0\n"},
+		{[]string{"run", "-e", "prints(args)", "a", "--engine=vm"}, "[a, --engine=vm]\n"},
+		{[]string{"run", "--engine=vm", "-e", "prints(args.length)"}, "0\n"},
+		{[]string{"run", "--engine=vm", "-e", "prints(args[0])", "x"}, "x\n"},
+		{[]string{"ast", "-e", "let x = 1; x * 2 + 1"}, "let x = 1;\n((x * 2) + 1)\n"},
+		{[]string{"tokens", "-e", "x;"}, "1:1      IDENT      \"x\"\n1:2      ;          \";\"\n1:3      EOF        \"\"\n"},
+		{[]string{"compile", "-e", "1 + 2"}, "<inline>: 8 bytes of instructions, 2 constants\n"},
+		{
+			[]string{"disasm", "-e", "1 + 2"},
+			"Constants:\n0000 INTEGER 1\n0001 INTEGER 2\nInstructions:\n0000 OpConstant 0\n0003 OpConstant 1\n0006 OpAdd\n0007 OpPop\n",
+		},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runCLI(tt.args...)
+		if code != ExitOK {
+			t.Errorf("bjs %s: exited with %d (stderr=%q)", strings.Join(tt.args, " "), code, stderr)
+			continue
+		}
+		if stdout != tt.expected {
+			t.Errorf("bjs %s: wrong output.\nwant=%q\ngot =%q", strings.Join(tt.args, " "), tt.expected, stdout)
+		}
+	}
+}
+
+func TestErrorsAreRenderedWithPosition(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"run", "-e", "let x = ;"},
+			"<inline>:1:9: no prefix parse function for ; found\n    let x = ;\n            ^\n",
+		},
+		{
+			[]string{"run", "-e", "let a = 1;\nlet b = a + true;"},
+			"<inline>:2:11: type mismatch: INTEGER + BOOLEAN\n    let b = a + true;\n              ^\n",
+		},
+		{
+			[]string{"run", "-e", "let x = 1;\n  prints(foobar)"},
+			"<inline>:2:10: identifier not found: foobar\n      prints(foobar)\n             ^^^^^^\n",
+		},
+		// Runtime errors of both engines are followed by the calls they unwound through
+		{
+			[]string{"run", "-e", "let f = fn(x) { x + true };\nf(1);"},
+			"<inline>:1:19: type mismatch: INTEGER + BOOLEAN\n    let f = fn(x) { x + true };\n                      ^\n    at f (<inline>:1:19)\n    at <inline>:2:2\n",
+		},
+		{
+			[]string{"run", "--engine=vm", "-e", "let f = fn(x) { x + true };\nf(1);"},
+			"<inline>:1:19: type mismatch: INTEGER + BOOLEAN\n    let f = fn(x) { x + true };\n                      ^\n    at f (<inline>:1:19)\n    at <inline>:2:2\n",
+		},
+	}
+	for _, tt := range tests {
+		code, _, stderr := runCLI(tt.args...)
+		if code != ExitFailure {
+			t.Errorf("bjs %s: wrong exit code. want=%d, got=%d", strings.Join(tt.args, " "), ExitFailure, code)
+		}
+		if stderr != tt.expected {
+			t.Errorf("bjs %s: wrong error output.\nwant=%q\ngot =%q", strings.Join(tt.args, " "), tt.expected, stderr)
+		}
+	}
+}
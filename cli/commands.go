@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"compiler/ast"
+	"compiler/compiler"
+	"compiler/constants"
+	"compiler/diagnostic"
+	"compiler/evaluator"
+	"compiler/lexer"
+	"compiler/object"
+	"compiler/parser"
+	"compiler/relp"
+	"compiler/token"
+	"compiler/virtualmachine"
+	"flag"
+	"fmt"
+)
+
+func runCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "run")
+	engine := engineFlag(fs)
+	overflow := overflowFlag(fs)
+	inline := fs.String("e", "", "run the given source instead of a file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if !validEngine(s, *engine) || !setOverflow(s, *overflow) {
+		return ExitUsage
+	}
+	filename, source, scriptArgs, code := readSource(s, fs, *inline)
+	if code != ExitOK {
+		return code
+	}
+	program, ok := parseSource(s, filename, source)
+	if !ok {
+		return ExitFailure
+	}
+	argv := argumentsArray(scriptArgs)
+	if *engine == EngineVM {
+		symbolTable := compiler.NewSymbolTableWithBuiltins()
+		argvSymbol := symbolTable.Define("args")
+		comp := compiler.NewWithState(symbolTable, []object.Object{})
+		if err := comp.Compile(program); err != nil {
+			reportError(s, filename, source, err)
+			return ExitFailure
+		}
+		globals := make([]object.Object, virtualmachine.GlobalsSize)
+		globals[argvSymbol.Index] = argv
+		machine := virtualmachine.NewWithGlobalsStore(comp.ByteCode(), globals)
+		if err := machine.Run(); err != nil {
+			if runtimeErr, ok := err.(*virtualmachine.RuntimeError); ok {
+				err = runtimeErr.Diagnostic()
+			}
+			reportError(s, filename, source, err)
+			return ExitFailure
+		}
+		return ExitOK
+	}
+	env := object.NewEnviornment()
+	env.Set("args", argv)
+	evaluated := evaluator.Eval(program, env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		reportError(s, filename, source, errObj.Diagnostic())
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// The arguments after the script are given to it as the args global, An array of strings. Flags of the run
+// command have to come before the script, Anything after it belongs to the script.
+func argumentsArray(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func replCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "repl")
+	engine := engineFlag(fs)
+	overflow := overflowFlag(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if !validEngine(s, *engine) || !setOverflow(s, *overflow) {
+		return ExitUsage
+	}
+	fmt.Fprint(s.stdout, constants.LOGO)
+	fmt.Fprintln(s.stdout, "JavaScript For Servers, Blazingly Fast and Compiled")
+	fmt.Fprintln(s.stdout, "Welcome to BJS lang debugger, This is your new RELP for debugging purpose")
+	if *engine == EngineVM {
+		fmt.Fprintln(s.stdout, "Running in Compilation mode")
+	} else {
+		fmt.Fprintln(s.stdout, "Running in Interpreter mode: default mode")
+	}
+	relp.StartRELP(s.stdin, s.stdout, *engine == EngineVM)
+	return ExitOK
+}
+
+func compileCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "compile")
+	inline := fs.String("e", "", "compile the given source instead of a file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	bytecode, filename, code := compileSource(s, fs, *inline)
+	if code != ExitOK {
+		return code
+	}
+	fmt.Fprintf(s.stdout, "%s: %d bytes of instructions, %d constants\n", filename, len(bytecode.Instructions), len(bytecode.Constants))
+	return ExitOK
+}
+
+func disasmCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "disasm")
+	inline := fs.String("e", "", "disassemble the given source instead of a file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	bytecode, _, code := compileSource(s, fs, *inline)
+	if code != ExitOK {
+		return code
+	}
+	fmt.Fprintln(s.stdout, "Constants:")
+	for i, constant := range bytecode.Constants {
+		fmt.Fprintf(s.stdout, "%04d %s %s\n", i, constant.Type(), constant.Inspect())
+	}
+	fmt.Fprintln(s.stdout, "Instructions:")
+	fmt.Fprint(s.stdout, bytecode.Instructions.String())
+	// Only programs with try statements have handlers
+	if len(bytecode.Handlers) > 0 {
+		fmt.Fprintln(s.stdout, "Handlers:")
+		for _, h := range bytecode.Handlers {
+			fmt.Fprintf(s.stdout, "%04d-%04d -> %04d depth %d\n", h.Start, h.End, h.Target, h.Depth)
+		}
+	}
+	return ExitOK
+}
+
+func tokensCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "tokens")
+	inline := fs.String("e", "", "tokenize the given source instead of a file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	_, source, _, code := readSource(s, fs, *inline)
+	if code != ExitOK {
+		return code
+	}
+	l := lexer.New(source)
+	for {
+		tok := l.NextToken()
+		fmt.Fprintf(s.stdout, "%-8s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	return ExitOK
+}
+
+func astCommand(s *streams, args []string) int {
+	fs := newFlagSet(s, "ast")
+	inline := fs.String("e", "", "parse the given source instead of a file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	filename, source, _, code := readSource(s, fs, *inline)
+	if code != ExitOK {
+		return code
+	}
+	program, ok := parseSource(s, filename, source)
+	if !ok {
+		return ExitFailure
+	}
+	lines := []string{}
+	for _, statement := range program.Statements {
+		lines = append(lines, statement.String())
+	}
+	printLines(s.stdout, lines)
+	return ExitOK
+}
+
+// Parses the source and expands its macros, Every parser error and the expansion error are reported on stderr
+func parseSource(s *streams, filename, source string) (*ast.Program, bool) {
+	p := parser.New(lexer.NewWithFile(filename, source))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintln(s.stderr, d.Render(source))
+		}
+		return nil, false
+	}
+	macros := object.NewEnviornment()
+	evaluator.DefineMacros(program, macros)
+	program, err := evaluator.ExpandMacros(program, macros)
+	if err != nil {
+		reportError(s, filename, source, err)
+		return nil, false
+	}
+	return program, true
+}
+
+// Reports an error on stderr, Diagnostics are rendered along with the source excerpt and the stack of runtime
+// errors. Any other error is prefixed with the file name.
+func reportError(s *streams, filename, source string, err error) {
+	if d, ok := err.(*diagnostic.Diagnostic); ok {
+		if d.Pos.File == "" {
+			d.Pos.File = filename
+		}
+		fmt.Fprintln(s.stderr, d.Render(source))
+		return
+	}
+	fmt.Fprintf(s.stderr, "%s: %s\n", filename, err)
+}
+
+// Reads, parses and compiles the source, Errors are reported on stderr and the exit code is returned back.
+func compileSource(s *streams, fs *flag.FlagSet, inline string) (*compiler.ByteCode, string, int) {
+	filename, source, _, code := readSource(s, fs, inline)
+	if code != ExitOK {
+		return nil, "", code
+	}
+	program, ok := parseSource(s, filename, source)
+	if !ok {
+		return nil, "", ExitFailure
+	}
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		reportError(s, filename, source, err)
+		return nil, "", ExitFailure
+	}
+	return comp.ByteCode(), filename, ExitOK
+}
@@ -5,8 +5,10 @@ package code
 
 import (
 	"bytes"
+	"compiler/token"
 	"encoding/binary"
 	"fmt"
+	"sort"
 )
 
 // This package has all of the bytecode we require for compiling the language.
@@ -34,6 +36,50 @@ const (
 	OpGreaterThan
 	OpMinus
 	OpBang
+	OpGreaterThanOrEqual
+	OpJumpIfFalseOrPop
+	OpJumpIfTrueOrPop
+	OpJump
+	OpJumpNotTruthy
+	OpNull
+	OpGetGlobal
+	OpSetGlobal
+	OpCall
+	OpReturnValue
+	OpReturn
+	OpGetLocal
+	OpSetLocal
+	OpClosure
+	OpGetFree
+	OpCurrentClosure
+	OpArray
+	OpHash
+	OpIndex
+	OpGetBuiltin
+	OpLessThan
+	OpLessThanOrEqual
+	OpIter
+	OpIterNext
+	OpAssignFree
+	OpSetIndex
+	OpDup2
+	OpCaptureLocal
+	OpCaptureFree
+	OpAssignGlobal
+	OpAssignLocal
+	OpCaptureGlobal
+	OpThrow
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpUnsignedShiftRight
+	OpBitNot
+	OpGetLateGlobal
+	OpAssignConstant
 )
 
 // Opcode definations, We will use this to create further instructions for CPU and debug
@@ -57,6 +103,116 @@ var definations = map[Opcode]*Defination{
 	OpGreaterThan: {"OpGreaterThan", []int{}},
 	OpMinus:       {"OpMinus", []int{}},
 	OpBang:        {"OpBang", []int{}},
+	// Compares the two values on top of the stack
+	OpGreaterThanOrEqual: {"OpGreaterThanOrEqual", []int{}},
+	// Jumps used by && and ||, When the value on top of the stack decides the result the jump is taken and the
+	// value is kept as the result, otherwise the value is popped and the right operand is evaluated
+	OpJumpIfFalseOrPop: {"OpJumpIfFalseOrPop", []int{2}},
+	OpJumpIfTrueOrPop:  {"OpJumpIfTrueOrPop", []int{2}},
+	// Jumps have the absolute position of the target instruction as operand
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpNull:          {"OpNull", []int{}},
+	// Globals are addressed by the index the symbol table gave to the identifier
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetGlobal: {"OpSetGlobal", []int{2}},
+	// Calls the closure below the arguments on the stack, The operand is the number of arguments
+	OpCall: {"OpCall", []int{1}},
+	// Returns from the current frame with the value on top of the stack or with null
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+	// Locals live on the stack above the base pointer of the frame, so one byte is enough for the index
+	OpGetLocal: {"OpGetLocal", []int{1}},
+	OpSetLocal: {"OpSetLocal", []int{1}},
+	// Wraps the compiled function constant into a closure, The second operand is the number of free
+	// variables on the stack that the closure captures
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	// Builds an array out of the elements on the stack, The operand is the number of elements
+	OpArray: {"OpArray", []int{2}},
+	// Builds a hash out of the keys and values on the stack, The operand is the number of keys and values
+	// together so it is always twice the number of pairs
+	OpHash: {"OpHash", []int{2}},
+	// Indexes the array or hash below the index on the stack
+	OpIndex: {"OpIndex", []int{}},
+	// Pushes the builtin function with the given index in the builtin registry
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+	// Less than comparisons have their own opcodes, so the operands are evaluated from left to right
+	OpLessThan:        {"OpLessThan", []int{}},
+	OpLessThanOrEqual: {"OpLessThanOrEqual", []int{}},
+	// Replaces the value on top of the stack with an iterator over it, The operand is 1 when the iterator
+	// returns back the keys and indexes instead of the values
+	OpIter: {"OpIter", []int{1}},
+	// Pushes the next value of the iterator on top of the stack, Once the iterator is done it jumps to the
+	// operand instead
+	OpIterNext: {"OpIterNext", []int{2}},
+	// Assignments pop the value on top of the stack into an existing binding, Unlike OpSetGlobal and
+	// OpSetLocal which declare a new binding they write into the cell of a captured variable
+	OpAssignFree:   {"OpAssignFree", []int{1}},
+	OpAssignGlobal: {"OpAssignGlobal", []int{2}},
+	OpAssignLocal:  {"OpAssignLocal", []int{1}},
+	// Stores the value on top of the stack into the array or hash at the index below it, The operand is 1
+	// when the old value is pushed back as result instead of the new one
+	OpSetIndex: {"OpSetIndex", []int{1}},
+	// Duplicates the two values on top of the stack, Used to read an index before assigning to it
+	OpDup2: {"OpDup2", []int{}},
+	// Push the cell of a local or free variable for OpClosure, Captured locals are moved into a cell so
+	// that the closure shares them with the function that declared them
+	OpCaptureLocal: {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:  {"OpCaptureFree", []int{1}},
+	// Globals declared inside of a block are captured like locals
+	OpCaptureGlobal: {"OpCaptureGlobal", []int{2}},
+	// Throws the value on top of the stack, The virtual machine continues at the handler of the instruction
+	OpThrow: {"OpThrow", []int{}},
+	// Remainder, exponentiation, bitwise and shift operators which pop two values and push the result
+	OpMod:                {"OpMod", []int{}},
+	OpPow:                {"OpPow", []int{}},
+	OpBitAnd:             {"OpBitAnd", []int{}},
+	OpBitOr:              {"OpBitOr", []int{}},
+	OpBitXor:             {"OpBitXor", []int{}},
+	OpShiftLeft:          {"OpShiftLeft", []int{}},
+	OpShiftRight:         {"OpShiftRight", []int{}},
+	OpUnsignedShiftRight: {"OpUnsignedShiftRight", []int{}},
+	// Inverts the bits of the integer on top of the stack
+	OpBitNot: {"OpBitNot", []int{}},
+	// Reads a global which was used before it was declared, The second operand is the constant with its name
+	// for the error raised when the global still has no value
+	OpGetLateGlobal: {"OpGetLateGlobal", []int{2, 2}},
+	// Raises the error of an assignment to a constant before its declaration was compiled, The cell of the
+	// constant is on top of the stack and the operand is the constant with its name
+	OpAssignConstant: {"OpAssignConstant", []int{2}},
+}
+
+// Handler is an entry of the exception handler table of a function, Exceptions raised by the instructions
+// from Start up to but not including End continue at Target. The values on the stack above the locals are cut
+// back to Depth before the exception is pushed for the handler. Handlers of inner try statements come first.
+type Handler struct {
+	Start  int
+	End    int
+	Target int
+	Depth  int
+}
+
+// Source position of the instructions starting at Offset, It holds for every instruction up to the offset of
+// the next entry
+type SourcePosition struct {
+	Offset int
+	Pos    token.Position
+}
+
+// Source map of the instructions of a function, The entries are ordered by their offsets. Used by the
+// virtual machine to tell where a runtime error happened.
+type SourceMap []SourcePosition
+
+// Returns back the position of the instruction at the offset, The position is invalid when the instruction
+// does not come from the source
+func (m SourceMap) PositionOf(offset int) token.Position {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+	return m[i-1].Pos
 }
 
 // Lookup returns the defination pointer or error if the opcode does not exist
@@ -69,6 +225,22 @@ func Lookup(op byte) (*Defination, error) {
 	return def, nil
 }
 
+// Checks that the operands fit into the operand widths of the opcode, Make truncates operands which do not
+// fit so the compiler checks them before it emits an instruction
+func CheckOperands(op Opcode, operands ...int) error {
+	def, err := Lookup(byte(op))
+	if err != nil {
+		return err
+	}
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		if o < 0 || o >= 1<<(8*width) {
+			return fmt.Errorf("operand %d of %s does not fit into %d bytes", o, def.Name, width)
+		}
+	}
+	return nil
+}
+
 // Creates an slice of byte which has opcode and operand and returns it back
 // Thsi function posses risk of generating empty bytecode instructions
 func Make(op Opcode, operands ...int) []byte {
@@ -93,6 +265,8 @@ func Make(op Opcode, operands ...int) []byte {
 		switch width {
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
 		}
 		offset += width
 	}
@@ -107,6 +281,8 @@ func ReadOperands(def *Defination, ins Instructions) ([]int, int) {
 		switch width {
 		case 2:
 			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
 		}
 		offset += width
 	}
@@ -117,6 +293,10 @@ func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
 
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
 // Gets the instruction and returns back string of the instruction
 func (ins Instructions) String() string {
 	var out bytes.Buffer
@@ -144,6 +324,8 @@ func (ins Instructions) fmtInstruction(def *Defination, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
 }
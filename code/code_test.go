@@ -1,6 +1,9 @@
 package code
 
-import "testing"
+import (
+	"compiler/token"
+	"testing"
+)
 
 func TestMake(t *testing.T) {
 	tests := []struct {
@@ -10,6 +13,8 @@ func TestMake(t *testing.T) {
 	}{
 		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
 	}
 	for _, tt := range tests {
 		instruction := Make(tt.op, tt.operands...)
@@ -29,12 +34,16 @@ func TestMake(t *testing.T) {
 func TestInstructionsString(t *testing.T) {
 	instructions := []Instructions{
 		Make(OpAdd),
+		Make(OpGetLocal, 1),
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
 	}
 	expected := `0000 OpAdd
-0001 OpConstant 2
-0004 OpConstant 65535
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
 `
 
 	concatted := Instructions{}
@@ -47,6 +56,29 @@ func TestInstructionsString(t *testing.T) {
 	}
 }
 
+func TestCheckOperands(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected string
+	}{
+		{OpConstant, []int{65535}, ""},
+		{OpJump, []int{65536}, "operand 65536 of OpJump does not fit into 2 bytes"},
+		{OpGetLocal, []int{256}, "operand 256 of OpGetLocal does not fit into 1 bytes"},
+		{OpClosure, []int{1, 255}, ""},
+		{OpClosure, []int{1, -1}, "operand -1 of OpClosure does not fit into 1 bytes"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if err := CheckOperands(tt.op, tt.operands...); err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("wrong error for %v. want=%q, got=%q", tt.operands, tt.expected, got)
+		}
+	}
+}
+
 func TestReadOperands(t *testing.T) {
 	tests := []struct {
 		op        Opcode
@@ -54,6 +86,8 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
 	}
 	for _, tt := range tests {
 		instruction := Make(tt.op, tt.operands...)
@@ -72,3 +106,26 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceMap(t *testing.T) {
+	first := token.Position{Line: 1, Column: 1}
+	second := token.Position{Line: 2, Column: 5}
+	m := SourceMap{{Offset: 0, Pos: first}, {Offset: 3, Pos: second}}
+	tests := []struct {
+		offset   int
+		expected token.Position
+	}{
+		{0, first},
+		{2, first},
+		{3, second},
+		{10, second},
+	}
+	for _, tt := range tests {
+		if pos := m.PositionOf(tt.offset); pos != tt.expected {
+			t.Errorf("wrong position of %d. want=%s, got=%s", tt.offset, tt.expected, pos)
+		}
+	}
+	if pos := (SourceMap{}).PositionOf(0); pos.IsValid() {
+		t.Errorf("empty source map has a position. got=%s", pos)
+	}
+}
@@ -0,0 +1,181 @@
+package compiler
+
+import (
+	"compiler/ast"
+	"compiler/code"
+	"compiler/diagnostic"
+	"compiler/object"
+)
+
+// Arithmetic opcodes of the compound assignments and updates
+var assignmentOperators = map[string]code.Opcode{
+	"+=": code.OpAdd,
+	"-=": code.OpSub,
+	"*=": code.OpMul,
+	"/=": code.OpDiv,
+	"++": code.OpAdd,
+	"--": code.OpSub,
+}
+
+// Assignment to a name stores the value and loads it back as the value of the expression
+//
+//	[<load name>]
+//	<value>
+//	[<operator>]
+//	<store name>
+//	<load name>
+//
+// Assignment to an index keeps the left side and the index on the stack for OpSetIndex
+//
+//	<left>
+//	<index>
+//	[OpDup2, OpIndex]
+//	<value>
+//	[<operator>]
+//	OpSetIndex 0
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	compound := node.Operator != "="
+	compileValue := func() error {
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		if compound {
+			c.emit(assignmentOperators[node.Operator])
+		}
+		return nil
+	}
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if c.compileUninitializedConstant(target) {
+			return nil
+		}
+		symbol, err := c.resolveAssignmentTarget(target)
+		if err != nil {
+			return err
+		}
+		if compound {
+			c.loadSymbol(symbol)
+		}
+		err = compileValue()
+		if err != nil {
+			return err
+		}
+		c.assignSymbol(symbol)
+		c.loadSymbol(symbol)
+		return nil
+	case *ast.IndexExpression:
+		err := c.compileIndexTarget(target, compound)
+		if err != nil {
+			return err
+		}
+		err = compileValue()
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpSetIndex, 0)
+		return nil
+	}
+	return diagnostic.New(node.Target.Pos(), "invalid assignment target %s", node.Target.String())
+}
+
+// Update adds or subtracts one, A postfix update leaves the old value on the stack
+//
+//	<load name>
+//	[<load name>]        postfix only, the first load is the result
+//	OpConstant 1
+//	<operator>
+//	<store name>
+//	[<load name>]        prefix only
+func (c *Compiler) compileUpdateExpression(node *ast.UpdateExpression) error {
+	operator := assignmentOperators[node.Operator]
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if c.compileUninitializedConstant(target) {
+			return nil
+		}
+		symbol, err := c.resolveAssignmentTarget(target)
+		if err != nil {
+			return err
+		}
+		c.loadSymbol(symbol)
+		if !node.Prefix {
+			c.loadSymbol(symbol)
+		}
+		c.emitOne()
+		c.emit(operator)
+		c.assignSymbol(symbol)
+		if node.Prefix {
+			c.loadSymbol(symbol)
+		}
+		return nil
+	case *ast.IndexExpression:
+		err := c.compileIndexTarget(target, true)
+		if err != nil {
+			return err
+		}
+		c.emitOne()
+		c.emit(operator)
+		keepOld := 1
+		if node.Prefix {
+			keepOld = 0
+		}
+		c.emit(code.OpSetIndex, keepOld)
+		return nil
+	}
+	return diagnostic.New(node.Target.Pos(), "invalid assignment target %s", node.Target.String())
+}
+
+// Compiles the left side and the index of an index assignment, With readCurrent the current value at the
+// index is pushed on top of them
+func (c *Compiler) compileIndexTarget(target *ast.IndexExpression, readCurrent bool) error {
+	err := c.Compile(target.Left)
+	if err != nil {
+		return err
+	}
+	err = c.Compile(target.Index)
+	if err != nil {
+		return err
+	}
+	if readCurrent {
+		c.emit(code.OpDup2)
+		c.emit(code.OpIndex)
+	}
+	return nil
+}
+
+// Names can only be assigned to when they were declared, Builtins can not be assigned to
+func (c *Compiler) resolveAssignmentTarget(target *ast.Identifier) (Symbol, error) {
+	symbol, ok := c.symbolTable.Resolve(target.Value)
+	if !ok || symbol.Scope == BuiltinScope || symbol.late {
+		return symbol, diagnostic.New(target.Pos(), "assignment to undeclared identifier: %s", target.Value)
+	}
+	if symbol.Constant {
+		return symbol, diagnostic.New(target.Pos(), "assignment to constant variable: %s", target.Value)
+	}
+	if symbol.Scope == FunctionScope {
+		return symbol, diagnostic.New(target.Pos(), "assignment to the function name %s is not supported", target.Value)
+	}
+	return symbol, nil
+}
+
+// Assignments to a constant which code compiled before its let statement makes always fail, But whether the
+// constant was declared when the assignment runs decides the error so it is raised at runtime
+//
+//	<capture name>
+//	OpAssignConstant <name>
+//	OpNull                   never reached, It stands for the value of the assignment
+func (c *Compiler) compileUninitializedConstant(target *ast.Identifier) bool {
+	symbol, ok := c.symbolTable.Resolve(target.Value)
+	if !ok || !symbol.Constant || !symbol.uninitialized {
+		return false
+	}
+	c.captureSymbol(symbol)
+	c.emit(code.OpAssignConstant, c.addConstant(&object.String{Value: symbol.Name}))
+	c.emit(code.OpNull)
+	return true
+}
+
+func (c *Compiler) emitOne() {
+	c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 1}))
+}
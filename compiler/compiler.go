@@ -4,14 +4,61 @@ package compiler
 import (
 	"compiler/ast"
 	"compiler/code"
+	"compiler/diagnostic"
 	"compiler/object"
+	"compiler/token"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // Compiler is a struct that contains bytecode instructions and constants
+// Every function literal is compiled in its own compilation scope, The outermost scope holds the
+// instructions of the main program.
 type Compiler struct {
-	instructions code.Instructions
-	constants    []object.Object
+	constants   []object.Object
+	symbolTable *SymbolTable
+	scopes      []CompilationScope
+	scopeIndex  int
+	// Let statements of blocks with function declarations are declared with the hoisted functions
+	predeclared map[*ast.LetStatement]Symbol
+	position    token.Position // Position of the node that is being compiled
+	// First operand that did not fit into its instruction, emit does not return errors so Compile reports it
+	operandError error
+}
+
+// Limits of the one byte operands, Locals are numbered from 0 to 255 and calls and closures count their
+// arguments and free variables in a byte
+const (
+	MaxLocals        = 256
+	MaxArguments     = 255
+	MaxFreeVariables = 255
+)
+
+// Compilation scope has the instructions of a single function, The last two emitted instructions are
+// tracked so that the compiler can fix up the value of blocks and function bodies.
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+	loops               []*loopContext
+	tries               []*tryContext
+	handlers            []code.Handler
+	positions           code.SourceMap
+	depth               int // Number of values on the stack above the locals after the last instruction
+}
+
+// Instruction that was emitted along with its position in the instructions
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+	Value    bool // OpPop of an expression statement, Pops such as the one of the iterator of a loop are not values
+}
+
+// Returns back the symbol table of the program, Passed to NewWithState to continue compiling with the same
+// bindings
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
 }
 
 // This is higher level abstraction for code, This is bytecode which has constant
@@ -19,23 +66,61 @@ type Compiler struct {
 type ByteCode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	Handlers     []code.Handler
+	Positions    code.SourceMap
 }
 
 // Creates and returns new compiler to compile the code
 // This is also used for testing
 func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
 	return &Compiler{
-		instructions: code.Instructions{},
-		constants:    []object.Object{},
+		constants:   []object.Object{},
+		symbolTable: NewSymbolTableWithBuiltins(),
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+		predeclared: map[*ast.LetStatement]Symbol{},
 	}
 }
 
-// Compiles the code and returns back if there is error
+// Creates a compiler which continues with the symbol table and constants of an earlier compilation, Used by
+// the REPL so that bindings of one input are available in the next one. The symbol table has to come from
+// NewSymbolTableWithBuiltins or from an earlier compiler.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+// Compiles the code and returns back if there is error, Errors are *diagnostic.Diagnostic values which
+// carry the position of the node that could not be compiled. Instructions get the position of the innermost
+// node they were emitted for, Like errors of the evaluator do.
 func (c *Compiler) Compile(node ast.Node) error {
+	previous := c.position
+	if pos := node.Pos(); pos.IsValid() {
+		c.position = pos
+	}
+	err := c.compileNode(node)
+	if err == nil && c.operandError != nil {
+		err, c.operandError = c.operandError, nil
+	}
+	c.position = previous
+	return err
+}
+
+func (c *Compiler) compileNode(node ast.Node) error {
 	// Get the node type
 	switch node := node.(type) {
 	// Check if it is AST Program node if so traverse the node statements
 	case *ast.Program:
+		if err := c.hoistFunctions(node.Statements); err != nil {
+			return err
+		}
 		for _, s := range node.Statements {
 			err := c.Compile(s)
 			if err != nil {
@@ -50,6 +135,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 		c.emit(code.OpPop)
+		c.scopes[c.scopeIndex].lastInstruction.Value = true
 	// Check for case for boolean values
 	case *ast.Boolean:
 		if node.Value {
@@ -59,20 +145,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 	// Get the left and right node for infix expression and compile them
 	case *ast.InfixExpression:
-		// Special case handling for less than operator
-		// Since we pop left and then right, If we do it in opposite direction
-		// In a hacky way we can use less than operator as greater than operator
-		if node.Operator == "<" {
-			err := c.Compile(node.Right)
-			if err != nil {
-				return err
-			}
-			err = c.Compile(node.Left)
-			if err != nil {
-				return err
-			}
-			c.emit(code.OpGreaterThan)
-			return nil
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
 		}
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -92,14 +166,36 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShiftLeft)
+		case ">>":
+			c.emit(code.OpShiftRight)
+		case ">>>":
+			c.emit(code.OpUnsignedShiftRight)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
 			c.emit(code.OpNotEqual)
 		default:
-			return fmt.Errorf("unknown operator founf %s", node.Operator)
+			return diagnostic.New(node.Pos(), "unknown operator %s", node.Operator)
 		}
 	// Added test case for prefix expression
 	case *ast.PrefixExpression:
@@ -112,8 +208,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpBang)
 		case "-":
 			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
 		default:
-			return fmt.Errorf("operator not supported %s", node.Operator)
+			return diagnostic.New(node.Pos(), "operator not supported %s", node.Operator)
 		}
 
 	// Get the node value and assign to integer
@@ -121,16 +219,389 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+	case *ast.BigIntLiteral:
+		bigInt := &object.BigInt{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(bigInt))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+	// Elements are pushed on the stack in order and OpArray collects them into an array
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		return c.compileHashLiteral(node)
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+	// Let binds the value to a global or local slot given by the symbol table, The name is defined after
+	// compiling the value so the value can still refer to an earlier binding with the same name.
+	case *ast.LetStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		if symbol, ok := c.predeclared[node]; ok {
+			c.storeSymbol(symbol)
+			c.symbolTable.initialize(node.Name.Value)
+			return nil
+		}
+		var symbol Symbol
+		if node.IsConst() {
+			symbol = c.symbolTable.DefineConstant(node.Name.Value)
+		} else {
+			symbol = c.symbolTable.Define(node.Name.Value)
+		}
+		c.storeSymbol(symbol)
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			// Like the interpreter the name only has to be declared once the code runs
+			symbol = c.symbolTable.DefineLate(node.Value)
+		}
+		c.loadSymbol(symbol)
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node)
+	case *ast.FunctionDeclaration:
+		// Compiled by hoistFunctions at the start of the block
+	case *ast.MacroLiteral:
+		return diagnostic.New(node.Pos(), "macros can only be bound with a top level let statement")
+	case *ast.ReturnStatement:
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+		if err := c.leaveTryStatements(len(c.scopes[c.scopeIndex].tries)); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+		c.resumeTryStatements()
+	case *ast.CallExpression:
+		if len(node.Arguments) > MaxArguments {
+			return diagnostic.New(node.Pos(), "too many arguments: %d, a call can pass at most %d", len(node.Arguments), MaxArguments)
+		}
+		err := c.Compile(node.Function)
+		if err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			err := c.Compile(a)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+	case *ast.BlockStatement:
+		if err := c.hoistFunctions(node.Statements); err != nil {
+			return err
+		}
+		for _, s := range node.Statements {
+			err := c.Compile(s)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+	case *ast.UpdateExpression:
+		return c.compileUpdateExpression(node)
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+	case *ast.ForEachStatement:
+		return c.compileForEachStatement(node)
+	case *ast.BreakStatement:
+		return c.compileLoopControl(node)
+	case *ast.ContinueStatement:
+		return c.compileLoopControl(node)
+	case *ast.ThrowStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+	case *ast.TryStatement:
+		return c.compileTryStatement(node)
+	default:
+		return diagnostic.New(node.Pos(), "compiling %s is not supported", strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."))
+	}
+	return nil
+}
+
+// If expression is compiled to a conditional jump over the consequence and a jump over the alternative,
+// Both branches leave a value on the stack, OpNull is used when there is no alternative.
+//
+//	<condition>
+//	OpJumpNotTruthy alternative
+//	<consequence>
+//	OpJump end
+//	alternative: <alternative> or OpNull
+//	end:
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	err = c.compileBlockValue(node.Consequence)
+	if err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	// The alternative starts without the value of the consequence
+	c.scopes[c.scopeIndex].depth--
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else {
+		err := c.compileBlockValue(node.Alternative)
+		if err != nil {
+			return err
+		}
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// Hash literal pushes every key followed by its value and OpHash collects them into a hash, The pairs of the
+// AST are kept in a map, so the keys are sorted to emit the same instructions on every compilation.
+func (c *Compiler) compileHashLiteral(node *ast.HashLiteral) error {
+	keys := []ast.Expression{}
+	for k := range node.Pairs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, k := range keys {
+		err := c.Compile(k)
+		if err != nil {
+			return err
+		}
+		err = c.Compile(node.Pairs[k])
+		if err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpHash, len(node.Pairs)*2)
+	return nil
+}
+
+// Function literal is compiled in its own scope into a compiled function constant, The free variables
+// are loaded onto the stack so that OpClosure can capture them.
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
+	if len(node.Parameters) > MaxArguments {
+		return diagnostic.New(node.Pos(), "too many parameters: %d, a function can have at most %d", len(node.Parameters), MaxArguments)
+	}
+	c.enterScope()
+	if node.Name != "" {
+		c.symbolTable.DefineFunctionName(node.Name)
+	}
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+	err := c.Compile(node.Body)
+	if err != nil {
+		c.leaveScope()
+		return err
+	}
+	if c.lastInstructionIsValue() {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	handlers := c.scopes[c.scopeIndex].handlers
+	positions := c.scopes[c.scopeIndex].positions
+	instructions := c.leaveScope()
+	for _, s := range freeSymbols {
+		c.captureSymbol(s)
+	}
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		Name:          node.Name,
+		Handlers:      handlers,
+		Positions:     positions,
+	}
+	fnIndex := c.addConstant(compiledFn)
+	c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+	return nil
+}
+
+// Function declarations are compiled at the start of their block, Every name of the block is declared as
+// uninitialized first so that the functions can capture each other and the lets of the block. Reading or
+// assigning a name before its let statement ran raises a ReferenceError like in the interpreter. The closures
+// and the values of the lets are stored into the cells the functions captured.
+//
+//	OpConstant <uninitialized>, <store name>   for every declaration and let
+//	<closure>, <store name>                    for every declaration
+func (c *Compiler) hoistFunctions(statements []ast.Statement) error {
+	declarations := []*ast.FunctionDeclaration{}
+	for _, statement := range statements {
+		if decl, ok := statement.(*ast.FunctionDeclaration); ok {
+			declarations = append(declarations, decl)
+		}
+	}
+	if len(declarations) == 0 {
+		return nil
+	}
+	symbols := map[*ast.FunctionDeclaration]Symbol{}
+	for _, statement := range statements {
+		var symbol Symbol
+		switch statement := statement.(type) {
+		case *ast.FunctionDeclaration:
+			symbol = c.symbolTable.Define(statement.Name.Value)
+			symbols[statement] = symbol
+		case *ast.LetStatement:
+			if statement.IsConst() {
+				symbol = c.symbolTable.DefineConstant(statement.Name.Value)
+			} else {
+				symbol = c.symbolTable.Define(statement.Name.Value)
+			}
+			symbol.uninitialized = true
+			c.symbolTable.store[statement.Name.Value] = symbol
+			c.predeclared[statement] = symbol
+		default:
+			continue
+		}
+		c.emit(code.OpConstant, c.addConstant(&object.Uninitialized{Name: symbol.Name}))
+		c.storeSymbol(symbol)
+	}
+	for _, decl := range declarations {
+		err := c.compileFunctionLiteral(decl.Function)
+		if err != nil {
+			return err
+		}
+		c.storeSymbol(symbols[decl])
 	}
 	return nil
 }
 
+// Emits the instruction that pushes the value of the symbol on the stack
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		if s.late {
+			c.emit(code.OpGetLateGlobal, s.Index, c.addConstant(&object.String{Value: s.Name}))
+		} else {
+			c.emit(code.OpGetGlobal, s.Index)
+		}
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	}
+}
+
+// Emits the instruction that binds the value on top of the stack to a symbol that was just declared
+func (c *Compiler) storeSymbol(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Index)
+	} else {
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+// Emits the instruction that assigns the value on top of the stack to an existing binding of the symbol
+func (c *Compiler) assignSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpAssignGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpAssignLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpAssignFree, s.Index)
+	}
+}
+
+// Emits the instruction that pushes a free variable of a new closure, Locals and free variables are
+// captured as cells so that assignments are shared between the closure and the enclosing function. Only
+// globals declared inside of a block are captured, Other globals are read directly.
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpCaptureGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
+// Compiles a block whose last expression is used as value, The OpPop of the last expression statement is
+// removed so the value stays on the stack. Blocks which do not end with an expression produce null.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	start := len(c.currentInstructions())
+	c.enterBlock()
+	err := c.Compile(block)
+	c.leaveBlock()
+	if err != nil {
+		return err
+	}
+	if c.lastInstructionIsValue() && c.scopes[c.scopeIndex].lastInstruction.Position >= start {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+// Compiles && and || with a conditional jump over the right operand, The jump target is not known while
+// emitting the jump so a placeholder is emitted and back-patched once the right operand is compiled.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+	jump := code.OpJumpIfFalseOrPop
+	if node.Operator == "||" {
+		jump = code.OpJumpIfTrueOrPop
+	}
+	jumpPos := c.emit(jump, 9999)
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
 // Returns bytecode struct, This struct has bytecode
 // Code has functions that generates instructions and constants
 func (c *Compiler) ByteCode() *ByteCode {
 	return &ByteCode{
-		Instructions: c.instructions,
+		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		Handlers:     c.scopes[c.scopeIndex].handlers,
+		Positions:    c.scopes[c.scopeIndex].positions,
 	}
 }
 
@@ -142,16 +613,144 @@ func (c *Compiler) addConstant(obj object.Object) int {
 
 // Creates instruction and returns back the position where instruction is set
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.checkOperands(op, operands...)
 	// Create instruction set from opcode and operands
 	ins := code.Make(op, operands...)
 	pos := c.addInstruction(ins)
+	c.addPosition(pos)
+	c.scopes[c.scopeIndex].depth += stackEffect(op, operands)
+	c.setLastInstruction(op, pos)
 	return pos
 }
 
+// Records the position of the compiled node for the instruction at the offset, Only changes of the position
+// are recorded
+func (c *Compiler) addPosition(offset int) {
+	positions := c.scopes[c.scopeIndex].positions
+	if len(positions) > 0 && positions[len(positions)-1].Pos == c.position {
+		return
+	}
+	c.scopes[c.scopeIndex].positions = append(positions, code.SourcePosition{Offset: offset, Pos: c.position})
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+// Checks if the last instruction is the OpPop of an expression statement, Its value is the value of the block
+func (c *Compiler) lastInstructionIsValue() bool {
+	return c.lastInstructionIs(code.OpPop) && c.scopes[c.scopeIndex].lastInstruction.Value
+}
+
+// Removes the last OpPop so that the value of the last expression stays on the stack
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+	c.scopes[c.scopeIndex].depth++
+	positions := c.scopes[c.scopeIndex].positions
+	for len(positions) > 0 && positions[len(positions)-1].Offset >= last.Position {
+		positions = positions[:len(positions)-1]
+	}
+	c.scopes[c.scopeIndex].positions = positions
+}
+
+// Replaces the last OpPop of a function body with OpReturnValue, so the value of the last expression is
+// returned back implicitly
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
 // Pushed instruction into slice and then return back the position of instruction where it is stored
 func (c *Compiler) addInstruction(ins []byte) int {
 	// Get the start of the instruction where it will be set
-	posNewInstruction := len(c.instructions)
-	c.instructions = append(c.instructions, ins...)
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
 	return posNewInstruction
 }
+
+// Replaces the instruction at the given position, The new instruction must have the same length
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+// Changes the operand of the instruction at the given position, Used for back-patching jump targets
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(op, operand)
+	newInstruction := code.Make(op, operand)
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// Records an error at the node that is being compiled when an operand is too large for its instruction, Like
+// a jump over more than 64 KB of bytecode or more than 65536 constants. Locals and free variables past the
+// limits of their one byte operands get their own message.
+func (c *Compiler) checkOperands(op code.Opcode, operands ...int) {
+	if c.operandError != nil {
+		return
+	}
+	err := code.CheckOperands(op, operands...)
+	if err == nil {
+		return
+	}
+	switch {
+	case op == code.OpGetLocal || op == code.OpSetLocal || op == code.OpAssignLocal || op == code.OpCaptureLocal:
+		c.operandError = diagnostic.New(c.position, "too many local variables, a function can have at most %d", MaxLocals)
+	case op == code.OpGetFree || op == code.OpAssignFree || op == code.OpCaptureFree ||
+		(op == code.OpClosure && operands[1] > MaxFreeVariables):
+		c.operandError = diagnostic.New(c.position, "too many free variables, a closure can capture at most %d", MaxFreeVariables)
+	default:
+		c.operandError = diagnostic.New(c.position, "program is too large: %s", err)
+	}
+}
+
+// Returns back the instructions of the scope that is being compiled
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// Enters a new compilation scope along with an enclosed symbol table, Used for function literals
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// Enters the scope of a block, Names declared until leaveBlock is called are only visible inside of it
+func (c *Compiler) enterBlock() {
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveBlock() {
+	c.symbolTable = c.symbolTable.Outer
+}
+
+// Leaves the current compilation scope and returns back its instructions
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+	return instructions
+}
@@ -7,6 +7,8 @@ import (
 	"compiler/object"
 	"compiler/parser"
 	"fmt"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -102,11 +104,11 @@ func TestIntegerArithmetic(t *testing.T) {
 		},
 		{
 			input:             "1 < 2",
-			expectedConstants: []interface{}{2, 1},
+			expectedConstants: []interface{}{1, 2},
 			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConstant, 0),
 				code.Make(code.OpConstant, 1),
-				code.Make(code.OpGreaterThan),
+				code.Make(code.OpLessThan),
 				code.Make(code.OpPop),
 			},
 		},
@@ -219,6 +221,35 @@ func testConstants(_ *testing.T, expected []interface{}, actual []object.Object)
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 
 			}
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testFloatObject failed: %s", i, err)
+			}
+		case *big.Int:
+			bigInt, ok := actual[i].(*object.BigInt)
+			if !ok || bigInt.Value.Cmp(constant) != 0 {
+				return fmt.Errorf("constant %d - not the big integer %s. got=%T (%+v)", i, constant, actual[i], actual[i])
+			}
+		case string:
+			err := testStringObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testStringObject failed: %s", i, err)
+			}
+		case *object.Uninitialized:
+			uninitialized, ok := actual[i].(*object.Uninitialized)
+			if !ok || uninitialized.Name != constant.Name {
+				return fmt.Errorf("constant %d - not uninitialized %s. got=%T (%+v)", i, constant.Name, actual[i], actual[i])
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				return fmt.Errorf("constant %d - not a function: %T", i, actual[i])
+			}
+			err := testInstructions(constant, fn.Instructions)
+			if err != nil {
+				return fmt.Errorf("constant %d - testInstructions failed: %s", i, err)
+			}
 		}
 	}
 	return nil
@@ -250,3 +281,1299 @@ func TestBooleanExpressions(t *testing.T) {
 	}
 	runCompilerTests(t, tests)
 }
+
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%f, want=%f", result.Value, expected)
+	}
+	return nil
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1.5 + 2",
+			expectedConstants: []interface{}{1.5, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "-0.5",
+			expectedConstants: []interface{}{0.5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 % 2 ** 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPow),
+				code.Make(code.OpMod),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 & 2 | 3 ^ 4",
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpBitAnd),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpBitXor),
+				code.Make(code.OpBitOr),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 << 2 >> 3 >>> 4",
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpShiftLeft),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpShiftRight),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpUnsignedShiftRight),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "~1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpBitNot),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestBigIntLiterals(t *testing.T) {
+	value, _ := new(big.Int).SetString("18446744073709551616", 10)
+	tests := []compilerTestCase{
+		{
+			input:             "18446744073709551616n * 2",
+			expectedConstants: []interface{}{value, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 <= 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThanOrEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 >= 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterThanOrEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "true && false; 3",
+			expectedConstants: []interface{}{3},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpIfFalseOrPop, 5),
+				// 0004
+				code.Make(code.OpFalse),
+				// 0005
+				code.Make(code.OpPop),
+				// 0006
+				code.Make(code.OpConstant, 0),
+				// 0009
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "false || true",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpFalse),
+				// 0001
+				code.Make(code.OpJumpIfTrueOrPop, 5),
+				// 0004
+				code.Make(code.OpTrue),
+				// 0005
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { 10 } else { 20 }; 3333;",
+			expectedConstants: []interface{}{10, 20, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 13),
+				// 0010
+				code.Make(code.OpConstant, 1),
+				// 0013
+				code.Make(code.OpPop),
+				// 0014
+				code.Make(code.OpConstant, 2),
+				// 0017
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { let a = 1; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpSetGlobal, 0),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpJump, 15),
+				// 0014
+				code.Make(code.OpNull),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let one = 1;
+			let two = 2;
+			`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+		{
+			input: `
+			let one = 1;
+			one;
+			`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let one = 1;
+			let two = one;
+			two;
+			`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break is only allowed inside a loop"},
+		{"x = 1;", "1:1: assignment to undeclared identifier: x"},
+		{"len += 1;", "1:1: assignment to undeclared identifier: len"},
+		{"const a = 1; a = 2;", "1:14: assignment to constant variable: a"},
+		{"const a = 1; fn() { a++ };", "1:21: assignment to constant variable: a"},
+		{"missing; missing = 1;", "1:10: assignment to undeclared identifier: missing"},
+		{"let f = fn() { let m = macro(x) { x } };", "1:24: macros can only be bound with a top level let statement"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		compiler := New()
+		err := compiler.Compile(program)
+		if err == nil {
+			t.Errorf("expected compiler error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestOperandLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"if (false) {" + strings.Repeat("1;", 16400) + "}",
+			"1:1: program is too large: operand 65606 of OpJumpNotTruthy does not fit into 2 bytes",
+		},
+		{
+			strings.Repeat("1;", 65537),
+			"1:131073: program is too large: operand 65536 of OpConstant does not fit into 2 bytes",
+		},
+		{
+			globals(65537),
+			"65537:1: program is too large: operand 65536 of OpSetGlobal does not fit into 2 bytes",
+		},
+		{
+			"let a = true; [" + strings.Repeat("a, ", 65535) + "a]",
+			"1:15: program is too large: operand 65536 of OpArray does not fit into 2 bytes",
+		},
+	}
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected compiler error for a program of %d bytes", len(tt.input))
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+// Declares the given number of globals
+func globals(count int) string {
+	var out strings.Builder
+	for _, name := range names("v", count) {
+		fmt.Fprintf(&out, "let %s = true;\n", name)
+	}
+	return out.String()
+}
+
+// Returns back the given number of identifiers, Identifiers can not have digits so the names are written in
+// letters after the prefix which keeps them apart from keywords
+func names(prefix string, count int) []string {
+	identifiers := make([]string, count)
+	for i := range identifiers {
+		name := ""
+		for n := i; n > 0 || name == ""; n /= 26 {
+			name = string(rune('a'+n%26)) + name
+		}
+		identifiers[i] = prefix + name
+	}
+	return identifiers
+}
+
+func TestByteOperandLimits(t *testing.T) {
+	locals := names("v", 300)
+	lets := ""
+	for _, name := range locals {
+		lets += "let " + name + " = 1;"
+	}
+	outer, inner := names("o", 200), names("u", 100)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"fn() {" + lets + "prints(va) }",
+			"1:3053: too many local variables, a function can have at most 256",
+		},
+		{
+			"fn(" + strings.Join(locals, ", ") + ") { va }",
+			"1:1: too many parameters: 300, a function can have at most 255",
+		},
+		{
+			"let f = fn() { 1 }; f(" + strings.Repeat("1, ", 299) + "1)",
+			"1:22: too many arguments: 300, a call can pass at most 255",
+		},
+		{
+			"fn(" + strings.Join(outer, ", ") + ") { fn(" + strings.Join(inner, ", ") + ") { fn() { [" +
+				strings.Join(append(outer, inner...), ", ") + "] } } }",
+			"1:2695: too many free variables, a closure can capture at most 255",
+		},
+	}
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected compiler error for %.40q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { return 5 + 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { 5 + 10 }`,
+			expectedConstants: []interface{}{
+				5,
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestFunctionCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn() { 24 }();`,
+			expectedConstants: []interface{}{
+				24,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let manyArg = fn(a, b, c) { a; b; c };
+			manyArg(24, 25, 26);
+			`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal, 2),
+					code.Make(code.OpReturnValue),
+				},
+				24,
+				25,
+				26,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpCall, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLetStatementScopes(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let num = 55;
+			fn() { num }
+			`,
+			expectedConstants: []interface{}{
+				55,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			fn() {
+				let a = 55;
+				let b = 77;
+				a + b
+			}
+			`,
+			expectedConstants: []interface{}{
+				55,
+				77,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			fn(a) {
+				fn(b) {
+					a + b
+				}
+			}
+			`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let countDown = fn(x) { countDown(x - 1); };
+			countDown(1);
+			`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpCurrentClosure),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestCompilerScopes(t *testing.T) {
+	compiler := New()
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. got=%d, want=%d", compiler.scopeIndex, 0)
+	}
+	globalSymbolTable := compiler.symbolTable
+	compiler.emit(code.OpMul)
+
+	compiler.enterScope()
+	if compiler.scopeIndex != 1 {
+		t.Errorf("scopeIndex wrong. got=%d, want=%d", compiler.scopeIndex, 1)
+	}
+	compiler.emit(code.OpSub)
+	if len(compiler.scopes[compiler.scopeIndex].instructions) != 1 {
+		t.Errorf("instructions length wrong. got=%d", len(compiler.scopes[compiler.scopeIndex].instructions))
+	}
+	if compiler.symbolTable.Outer != globalSymbolTable {
+		t.Errorf("compiler did not enclose symbolTable")
+	}
+
+	compiler.leaveScope()
+	if compiler.scopeIndex != 0 {
+		t.Errorf("scopeIndex wrong. got=%d, want=%d", compiler.scopeIndex, 0)
+	}
+	if compiler.symbolTable != globalSymbolTable {
+		t.Errorf("compiler did not restore global symbol table")
+	}
+	compiler.emit(code.OpAdd)
+	if len(compiler.scopes[compiler.scopeIndex].instructions) != 2 {
+		t.Errorf("instructions length wrong. got=%d", len(compiler.scopes[compiler.scopeIndex].instructions))
+	}
+	last := compiler.scopes[compiler.scopeIndex].lastInstruction
+	if last.Opcode != code.OpAdd {
+		t.Errorf("lastInstruction.Opcode wrong. got=%d, want=%d", last.Opcode, code.OpAdd)
+	}
+	previous := compiler.scopes[compiler.scopeIndex].previousInstruction
+	if previous.Opcode != code.OpMul {
+		t.Errorf("previousInstruction.Opcode wrong. got=%d, want=%d", previous.Opcode, code.OpMul)
+	}
+}
+
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+	}
+	return nil
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"bjs"`,
+			expectedConstants: []interface{}{"bjs"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"b" + "js"`,
+			expectedConstants: []interface{}{"b", "js"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[]",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "[1, 2 + 3]",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "{}",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpHash, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "{3: 4, 1: 2}",
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[1, 2][1]",
+			expectedConstants: []interface{}{1, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "{1: 2}[1]",
+			expectedConstants: []interface{}{1, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `len([]); push([], 1);`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetBuiltin, 4),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCall, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `fn() { len([]) }`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestCompilerWithState(t *testing.T) {
+	first := New()
+	if err := first.Compile(parse("let a = 1;")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := first.ByteCode()
+	second := NewWithState(first.SymbolTable(), bytecode.Constants)
+	if err := second.Compile(parse("a + 2")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	err := testInstructions([]code.Instructions{
+		code.Make(code.OpGetGlobal, 0),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpPop),
+	}, second.ByteCode().Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+	err = testConstants(t, []interface{}{1, 2}, second.ByteCode().Constants)
+	if err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "while (true) { break; }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpJump, 10),
+				// 0007
+				code.Make(code.OpJump, 0),
+			},
+		},
+		{
+			input:             "for (let i = 0; i < 2; i++) { continue; }",
+			expectedConstants: []interface{}{0, 2, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpSetGlobal, 0),
+				// 0012
+				code.Make(code.OpGetGlobal, 0),
+				// 0015
+				code.Make(code.OpConstant, 1),
+				// 0018
+				code.Make(code.OpLessThan),
+				// 0019
+				code.Make(code.OpJumpNotTruthy, 48),
+				// 0022
+				code.Make(code.OpJump, 25),
+				// 0025
+				code.Make(code.OpGetGlobal, 0),
+				// 0028
+				code.Make(code.OpSetGlobal, 0),
+				// 0031
+				code.Make(code.OpGetGlobal, 0),
+				// 0034
+				code.Make(code.OpGetGlobal, 0),
+				// 0037
+				code.Make(code.OpConstant, 2),
+				// 0040
+				code.Make(code.OpAdd),
+				// 0041
+				code.Make(code.OpAssignGlobal, 0),
+				// 0044
+				code.Make(code.OpPop),
+				// 0045
+				code.Make(code.OpJump, 12),
+			},
+		},
+		{
+			input:             "for (x of [1]) { x; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpIter, 0),
+				// 0008
+				code.Make(code.OpIterNext, 21),
+				// 0011
+				code.Make(code.OpSetGlobal, 0),
+				// 0014
+				code.Make(code.OpGetGlobal, 0),
+				// 0017
+				code.Make(code.OpPop),
+				// 0018
+				code.Make(code.OpJump, 8),
+				// 0021
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let x = 1; x += 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpAssignGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "let x = 1; x++;",
+			expectedConstants: []interface{}{1, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpAssignGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "let a = [1]; a[0] *= 3;",
+			expectedConstants: []interface{}{1, 0, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDup2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpMul),
+				code.Make(code.OpSetIndex, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn() { let a = 1; fn() { a = 2; } }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAssignFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let a = 1; if (true) { let a = 2; a }",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 22),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpJump, 23),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "while (true) { let a = 1; fn() { a } }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 21),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpCaptureGlobal, 0),
+				code.Make(code.OpClosure, 1, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 0),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "f(); function f() { 1 }",
+			expectedConstants: []interface{}{
+				&object.Uninitialized{Name: "f"},
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn() { function get() { x } let x = 1; get() }",
+			expectedConstants: []interface{}{
+				&object.Uninitialized{Name: "get"},
+				&object.Uninitialized{Name: "x"},
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpCaptureLocal, 1),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 4, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "let f = x => x;",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestTryStatements(t *testing.T) {
+	tests := []struct {
+		compilerTestCase
+		expectedHandlers []code.Handler
+	}{
+		{
+			compilerTestCase{
+				input:             "try { 1 } catch (e) { e } finally { 2 }",
+				expectedConstants: []interface{}{1, 2, 2, 2},
+				expectedInstructions: []code.Instructions{
+					// 0000
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpPop),
+					// 0004
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpPop),
+					code.Make(code.OpJump, 30),
+					// 0011
+					code.Make(code.OpSetGlobal, 0),
+					// 0014
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpPop),
+					// 0018
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpPop),
+					code.Make(code.OpJump, 30),
+					// 0025
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpPop),
+					code.Make(code.OpThrow),
+				},
+			},
+			[]code.Handler{{Start: 0, End: 4, Target: 11, Depth: 0}, {Start: 14, End: 18, Target: 25, Depth: 0}},
+		},
+		{
+			compilerTestCase{
+				input:             "while (true) { try { 1; break } finally { 3 } }",
+				expectedConstants: []interface{}{1, 3, 3, 3},
+				expectedInstructions: []code.Instructions{
+					// 0000
+					code.Make(code.OpTrue),
+					code.Make(code.OpJumpNotTruthy, 30),
+					// 0004
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpPop),
+					// 0008 finally of the break
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpPop),
+					code.Make(code.OpJump, 30),
+					// 0015
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpPop),
+					code.Make(code.OpJump, 27),
+					// 0022
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpPop),
+					code.Make(code.OpThrow),
+					// 0027
+					code.Make(code.OpJump, 0),
+				},
+			},
+			[]code.Handler{{Start: 4, End: 8, Target: 22, Depth: 0}},
+		},
+		{
+			compilerTestCase{
+				input:             "for (x of [1]) { try { throw x } catch { } }",
+				expectedConstants: []interface{}{1},
+				expectedInstructions: []code.Instructions{
+					// 0000
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpArray, 1),
+					code.Make(code.OpIter, 0),
+					// 0008
+					code.Make(code.OpIterNext, 28),
+					code.Make(code.OpSetGlobal, 0),
+					// 0014
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpThrow),
+					code.Make(code.OpJump, 25),
+					// 0021
+					code.Make(code.OpPop),
+					code.Make(code.OpJump, 25),
+					// 0025
+					code.Make(code.OpJump, 8),
+					// 0028
+					code.Make(code.OpPop),
+				},
+			},
+			[]code.Handler{{Start: 14, End: 18, Target: 21, Depth: 1}},
+		},
+	}
+	for _, tt := range tests {
+		runCompilerTests(t, []compilerTestCase{tt.compilerTestCase})
+		compiler := New()
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		handlers := compiler.ByteCode().Handlers
+		if len(handlers) != len(tt.expectedHandlers) {
+			t.Fatalf("wrong number of handlers. want=%d, got=%d", len(tt.expectedHandlers), len(handlers))
+		}
+		for i, h := range tt.expectedHandlers {
+			if handlers[i] != h {
+				t.Errorf("wrong handler %d. want=%+v, got=%+v", i, h, handlers[i])
+			}
+		}
+	}
+}
+
+func TestLateGlobals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// The late global of g gets the slot the let statement declares afterwards
+			input: "let f = fn() { g }; let g = 1; g",
+			expectedConstants: []interface{}{
+				"g",
+				[]code.Instructions{
+					code.Make(code.OpGetLateGlobal, 0, 0),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (false) { missing }",
+			expectedConstants: []interface{}{"missing"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpNotTruthy, 12),
+				code.Make(code.OpGetLateGlobal, 0, 0),
+				code.Make(code.OpJump, 13),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestSourcePositions(t *testing.T) {
+	comp := New()
+	if err := comp.Compile(parse("1 + 2;\nlet f = fn() { -3 };")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.ByteCode()
+	fn := bytecode.Constants[len(bytecode.Constants)-1].(*object.CompiledFunction)
+	tests := []struct {
+		positions code.SourceMap
+		offset    int
+		expected  string
+	}{
+		// OpConstant of the operands and OpAdd of the operator
+		{bytecode.Positions, 0, "1:1"},
+		{bytecode.Positions, 3, "1:5"},
+		{bytecode.Positions, 6, "1:3"},
+		// OpClosure at the function literal and OpSetGlobal at the let statement
+		{bytecode.Positions, 8, "2:9"},
+		{bytecode.Positions, 12, "2:1"},
+		// OpMinus inside of the function
+		{fn.Positions, 3, "2:16"},
+	}
+	for _, tt := range tests {
+		if pos := tt.positions.PositionOf(tt.offset); pos.String() != tt.expected {
+			t.Errorf("wrong position at %d. want=%s, got=%s", tt.offset, tt.expected, pos)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package compiler
+
+import (
+	"compiler/ast"
+	"compiler/code"
+)
+
+// Try context tracks the instructions protected by a try statement while its block or its catch block is
+// compiled, Jumps and returns which leave the try statement run its finally block before they leave. The
+// copies of the finally block are not protected by the try statement, so the protected instructions are
+// interrupted around them and continue after the jump.
+type tryContext struct {
+	finally *ast.BlockStatement
+	loops   int            // Number of loops around the try statement, Only break and continue of the innermost loop leave it
+	start   int            // Start of the protected instructions, -1 while they are interrupted
+	ranges  []code.Handler // Protected instructions, The target and depth are filled in once they are known
+}
+
+// Stops protecting instructions at the position
+func (t *tryContext) interrupt(pos int) {
+	if t.start != -1 && pos > t.start {
+		t.ranges = append(t.ranges, code.Handler{Start: t.start, End: pos})
+	}
+	t.start = -1
+}
+
+// Try statement has an exception handler for its block and one for its catch block when it also has a finally
+// block. The finally block is copied to the end of every way out of the statement, The copy after the rethrow
+// label runs with the exception on the stack and throws it again.
+//
+//	<block>                    handler: catch or rethrow
+//	[<finally>]
+//	OpJump end
+//	catch: <bind exception>    only with a catch block
+//	<catch block>              handler: rethrow when there is a finally block
+//	[<finally>]
+//	OpJump end
+//	rethrow: <finally>         only with a finally block
+//	OpThrow
+//	end:
+func (c *Compiler) compileTryStatement(node *ast.TryStatement) error {
+	depth := c.scopes[c.scopeIndex].depth
+	ends := []int{}
+	ranges, err := c.compileProtected(node.Block, node.Finally)
+	if err != nil {
+		return err
+	}
+	if node.Finally != nil {
+		if err := c.compileBlock(node.Finally); err != nil {
+			return err
+		}
+	}
+	ends = append(ends, c.emit(code.OpJump, 9999))
+	if node.Catch != nil {
+		c.startHandler(ranges, depth)
+		ranges, err = c.compileCatch(node)
+		if err != nil {
+			return err
+		}
+		if node.Finally != nil {
+			if err := c.compileBlock(node.Finally); err != nil {
+				return err
+			}
+		}
+		// The jump also keeps the last expression statement of the catch block from being used as value
+		ends = append(ends, c.emit(code.OpJump, 9999))
+	}
+	if node.Finally != nil {
+		c.startHandler(ranges, depth)
+		if err := c.compileBlock(node.Finally); err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+	}
+	for _, pos := range ends {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// The exception is bound to the parameter or popped when there is none, The catch block is only protected
+// when the finally block has to run after an exception of the catch block.
+func (c *Compiler) compileCatch(node *ast.TryStatement) ([]code.Handler, error) {
+	c.enterBlock()
+	defer c.leaveBlock()
+	if node.Param != nil {
+		c.storeSymbol(c.symbolTable.Define(node.Param.Value))
+	} else {
+		c.emit(code.OpPop)
+	}
+	if node.Finally == nil {
+		return nil, c.compileBlock(node.Catch)
+	}
+	return c.compileProtected(node.Catch, node.Finally)
+}
+
+// Compiles the block in a new try context and returns back the instructions it protects
+func (c *Compiler) compileProtected(block, finally *ast.BlockStatement) ([]code.Handler, error) {
+	scope := &c.scopes[c.scopeIndex]
+	try := &tryContext{finally: finally, loops: len(scope.loops), start: len(scope.instructions)}
+	scope.tries = append(scope.tries, try)
+	err := c.compileBlock(block)
+	scope = &c.scopes[c.scopeIndex]
+	scope.tries = scope.tries[:len(scope.tries)-1]
+	try.interrupt(len(scope.instructions))
+	return try.ranges, err
+}
+
+// Adds the handlers of the protected instructions with the current position as target, The code at the target
+// starts with the exception on top of the stack.
+func (c *Compiler) startHandler(ranges []code.Handler, depth int) {
+	scope := &c.scopes[c.scopeIndex]
+	for _, r := range ranges {
+		r.Target = len(scope.instructions)
+		r.Depth = depth
+		scope.handlers = append(scope.handlers, r)
+	}
+	scope.depth = depth + 1
+}
+
+// Compiles the block with its own names
+func (c *Compiler) compileBlock(block *ast.BlockStatement) error {
+	c.enterBlock()
+	defer c.leaveBlock()
+	return c.Compile(block)
+}
+
+// Runs the finally blocks of the innermost try statements which a jump or return leaves, The try statements
+// are interrupted before the first finally block that runs after their own. A finally block is compiled with
+// only the try statements around its own, So a jump inside of it leaves those.
+func (c *Compiler) leaveTryStatements(count int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	outermost := -1
+	for i := len(tries) - count; i < len(tries); i++ {
+		if tries[i].finally != nil {
+			outermost = i
+			break
+		}
+	}
+	if outermost == -1 {
+		return nil
+	}
+	for i := len(tries) - 1; i >= outermost; i-- {
+		tries[i].interrupt(len(c.currentInstructions()))
+		if tries[i].finally == nil {
+			continue
+		}
+		c.scopes[c.scopeIndex].tries = tries[:i]
+		err := c.compileBlock(tries[i].finally)
+		c.scopes[c.scopeIndex].tries = tries
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Continues protecting instructions after the jump or return which interrupted the try statements
+func (c *Compiler) resumeTryStatements() {
+	for _, try := range c.scopes[c.scopeIndex].tries {
+		if try.start == -1 {
+			try.start = len(c.currentInstructions())
+		}
+	}
+}
+
+// Number of try statements inside of the innermost loop, break and continue leave them
+func (c *Compiler) triesInLoop() int {
+	scope := c.scopes[c.scopeIndex]
+	count := 0
+	for i := len(scope.tries) - 1; i >= 0 && scope.tries[i].loops == len(scope.loops); i-- {
+		count++
+	}
+	return count
+}
+
+// Number of values the instruction pushes minus the number of values it pops, Jumps that are taken are not
+// considered. The compiler uses it to know the depth of the stack at the start of a handler.
+func stackEffect(op code.Opcode, operands []int) int {
+	switch op {
+	case code.OpConstant, code.OpTrue, code.OpFalse, code.OpNull, code.OpGetGlobal, code.OpGetLocal,
+		code.OpGetFree, code.OpCurrentClosure, code.OpGetBuiltin, code.OpIterNext, code.OpCaptureLocal,
+		code.OpCaptureFree, code.OpCaptureGlobal, code.OpGetLateGlobal:
+		return 1
+	case code.OpDup2:
+		return 2
+	case code.OpCall:
+		return -operands[0]
+	case code.OpClosure:
+		return 1 - operands[1]
+	case code.OpArray, code.OpHash:
+		return 1 - operands[0]
+	case code.OpSetIndex:
+		return -2
+	case code.OpMinus, code.OpBang, code.OpBitNot, code.OpJump, code.OpReturn, code.OpIter:
+		return 0
+	}
+	// Binary operators, stores, conditional jumps, OpPop, OpReturnValue and OpThrow pop one value
+	return -1
+}
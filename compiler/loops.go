@@ -0,0 +1,202 @@
+package compiler
+
+import (
+	"compiler/ast"
+	"compiler/code"
+	"compiler/diagnostic"
+)
+
+// Loop context collects the jumps of break and continue statements of a loop, Their targets are only known
+// once the body is compiled so they are back-patched afterwards.
+type loopContext struct {
+	breaks    []int
+	continues []int
+	depth     int // Depth of the stack in the body, Values above it are popped before jumping out of the body
+}
+
+// While loop jumps back to the condition after every run of the body
+//
+//	start: <condition>
+//	OpJumpNotTruthy end
+//	<body>
+//	OpJump start
+//	end:
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	start := len(c.currentInstructions())
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	loop, err := c.compileLoopBody(node.Body)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpJump, start)
+	end := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthyPos, end)
+	c.patchLoop(loop, start, end)
+	return nil
+}
+
+// For loop runs the init once and the update after every run of the body, continue jumps to the update.
+// The variable declared in the init is declared again with its current value before every run so closures
+// of the body keep the value of their run like in JavaScript.
+//
+//	<init>
+//	<copy>
+//	start: <condition>
+//	OpJumpNotTruthy end
+//	<body>
+//	update: <copy>
+//	<update>
+//	OpJump start
+//	end:
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	// Names declared in the init statement are visible in the whole loop
+	c.enterBlock()
+	defer c.leaveBlock()
+	var variable *Symbol
+	if node.Init != nil {
+		err := c.Compile(node.Init)
+		if err != nil {
+			return err
+		}
+		if let, ok := node.Init.(*ast.LetStatement); ok {
+			if s, ok := c.symbolTable.Resolve(let.Name.Value); ok {
+				variable = &s
+			}
+		}
+	}
+	c.copyLoopVariable(variable)
+	start := len(c.currentInstructions())
+	jumpNotTruthyPos := -1
+	if node.Condition != nil {
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		jumpNotTruthyPos = c.emit(code.OpJumpNotTruthy, 9999)
+	}
+	loop, err := c.compileLoopBody(node.Body)
+	if err != nil {
+		return err
+	}
+	update := len(c.currentInstructions())
+	c.copyLoopVariable(variable)
+	if node.Update != nil {
+		err := c.Compile(node.Update)
+		if err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpJump, start)
+	end := len(c.currentInstructions())
+	if jumpNotTruthyPos != -1 {
+		c.changeOperand(jumpNotTruthyPos, end)
+	}
+	c.patchLoop(loop, update, end)
+	return nil
+}
+
+// Declares the loop variable again with its current value, Closures which captured the old binding keep it
+func (c *Compiler) copyLoopVariable(variable *Symbol) {
+	if variable == nil {
+		return
+	}
+	c.loadSymbol(*variable)
+	c.storeSymbol(*variable)
+}
+
+// For each loop keeps the iterator on the stack while the loop runs, It is popped once the iterator is done
+// or the loop is left with break.
+//
+//	<iterable>
+//	OpIter
+//	start: OpIterNext end
+//	<set variable>
+//	<body>
+//	OpJump start
+//	end: OpPop
+func (c *Compiler) compileForEachStatement(node *ast.ForEachStatement) error {
+	err := c.Compile(node.Iterable)
+	if err != nil {
+		return err
+	}
+	keys := 0
+	if node.Operator == "in" {
+		keys = 1
+	}
+	c.emit(code.OpIter, keys)
+	start := c.emit(code.OpIterNext, 9999)
+	// The variable is declared again on every run so closures of the body capture the value of their run
+	c.enterBlock()
+	defer c.leaveBlock()
+	var symbol Symbol
+	if node.Constant {
+		symbol = c.symbolTable.DefineConstant(node.Variable.Value)
+	} else {
+		symbol = c.symbolTable.Define(node.Variable.Value)
+	}
+	c.storeSymbol(symbol)
+	loop, err := c.compileLoopBody(node.Body)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpJump, start)
+	end := len(c.currentInstructions())
+	c.changeOperand(start, end)
+	c.emit(code.OpPop)
+	c.patchLoop(loop, start, end)
+	return nil
+}
+
+// Compiles the body of a loop in its own block with a new loop context for its break and continue statements
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement) (*loopContext, error) {
+	scope := &c.scopes[c.scopeIndex]
+	loop := &loopContext{depth: scope.depth}
+	scope.loops = append(scope.loops, loop)
+	c.enterBlock()
+	err := c.Compile(body)
+	c.leaveBlock()
+	scope = &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+	return loop, err
+}
+
+// Emits the jump of a break or continue statement, The target is back-patched by patchLoop. Before the jump the
+// values above the body of the loop are popped and the finally blocks of the try statements it leaves run.
+func (c *Compiler) compileLoopControl(node ast.Statement) error {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return diagnostic.New(node.Pos(), "%s is only allowed inside a loop", node.TokenLiteral())
+	}
+	loop := loops[len(loops)-1]
+	// Values of the enclosing expressions are popped, The code after the jump continues with them
+	depth := c.scopes[c.scopeIndex].depth
+	for i := loop.depth; i < depth; i++ {
+		c.emit(code.OpPop)
+	}
+	if err := c.leaveTryStatements(c.triesInLoop()); err != nil {
+		return err
+	}
+	pos := c.emit(code.OpJump, 9999)
+	c.resumeTryStatements()
+	c.scopes[c.scopeIndex].depth = depth
+	if _, ok := node.(*ast.BreakStatement); ok {
+		loop.breaks = append(loop.breaks, pos)
+	} else {
+		loop.continues = append(loop.continues, pos)
+	}
+	return nil
+}
+
+// Points the break statements of the loop to the end and the continue statements to the continue target
+func (c *Compiler) patchLoop(loop *loopContext, continueTarget, end int) {
+	for _, pos := range loop.breaks {
+		c.changeOperand(pos, end)
+	}
+	for _, pos := range loop.continues {
+		c.changeOperand(pos, continueTarget)
+	}
+}
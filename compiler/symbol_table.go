@@ -0,0 +1,200 @@
+package compiler
+
+import (
+	"compiler/object"
+	"sort"
+)
+
+// Symbol table keeps track of the identifiers the compiler has seen, Every identifier gets an index which is
+// used as operand for the instructions that read and write the value, so names are never needed at runtime.
+// Every function literal gets its own symbol table enclosing the table of the surrounding code, Identifiers
+// resolved through an enclosing function become free variables which are captured by the closure.
+// Blocks of if expressions and loops get a block table, Its names get slots of the enclosing function or of
+// the globals and shadow the names of the enclosing tables until the block ends. Names which are used before
+// they are declared get a late global, The program can still declare them later like in the interpreter.
+
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+	BuiltinScope  SymbolScope = "BUILTIN"
+)
+
+// Symbol has the information needed by the compiler for emitting instructions for an identifier
+type Symbol struct {
+	Name     string
+	Scope    SymbolScope
+	Index    int
+	Constant bool // Declared with const, The compiler rejects assignments to it
+	block    bool // Global declared inside of a block, Functions capture it like a local
+	late     bool // Global used before it was declared, Reading it checks that it has a value
+	// Let of a block with function declarations whose statement was not compiled yet, Code compiled before it
+	// may run before the binding has a value
+	uninitialized bool
+}
+
+type SymbolTable struct {
+	Outer          *SymbolTable
+	FreeSymbols    []Symbol
+	store          map[string]Symbol
+	numDefinitions int
+	block          bool
+}
+
+// Creates a new empty symbol table
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{store: make(map[string]Symbol), FreeSymbols: []Symbol{}}
+}
+
+// Creates the global symbol table of a program with every builtin function defined
+func NewSymbolTableWithBuiltins() *SymbolTable {
+	s := NewSymbolTable()
+	for i, def := range object.Builtins {
+		s.DefineBuiltin(i, def.Name)
+	}
+	return s
+}
+
+// Returns back a copy of the table, Used to roll back the definitions of an input that failed
+func (s *SymbolTable) Copy() *SymbolTable {
+	copied := &SymbolTable{
+		Outer:          s.Outer,
+		FreeSymbols:    append([]Symbol{}, s.FreeSymbols...),
+		store:          make(map[string]Symbol, len(s.store)),
+		numDefinitions: s.numDefinitions,
+		block:          s.block,
+	}
+	for name, symbol := range s.store {
+		copied.store[name] = symbol
+	}
+	return copied
+}
+
+// Returns back the symbols defined in this table sorted by name, Symbols of enclosing tables and late globals
+// which were never declared are left out
+func (s *SymbolTable) Symbols() []Symbol {
+	symbols := make([]Symbol, 0, len(s.store))
+	for _, symbol := range s.store {
+		if !symbol.late {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Name < symbols[j].Name
+	})
+	return symbols
+}
+
+// Creates the symbol table of a function enclosed by the symbol table of the surrounding code
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Creates the table of a block enclosed by the table of the surrounding code
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewEnclosedSymbolTable(outer)
+	s.block = true
+	return s
+}
+
+// Defines the identifier and returns back its symbol, Defining a name twice returns back the existing symbol
+// so that a second let binding overwrites the value in the same slot. That happens in the REPL and for late
+// globals, The parser does not allow a name to be declared twice in the same block.
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		symbol.Constant = false
+		symbol.late = false
+		s.store[name] = symbol
+		return symbol
+	}
+	// Names of blocks get their slots from the function or the program the block belongs to
+	owner := s
+	for owner.block {
+		owner = owner.Outer
+	}
+	symbol := Symbol{Name: name, Index: owner.numDefinitions}
+	if owner.Outer == nil {
+		symbol.Scope = GlobalScope
+		symbol.block = s.block
+	} else {
+		symbol.Scope = LocalScope
+	}
+	s.store[name] = symbol
+	owner.numDefinitions++
+	return symbol
+}
+
+// Defines the identifier as a constant
+func (s *SymbolTable) DefineConstant(name string) Symbol {
+	symbol := s.Define(name)
+	symbol.Constant = true
+	s.store[name] = symbol
+	return symbol
+}
+
+// Marks the symbol of the name as declared by its let statement
+func (s *SymbolTable) initialize(name string) {
+	symbol := s.store[name]
+	symbol.uninitialized = false
+	s.store[name] = symbol
+}
+
+// Defines a late global for a name that could not be resolved, It belongs to the outermost table so a top level
+// let statement further down declares the same slot.
+func (s *SymbolTable) DefineLate(name string) Symbol {
+	global := s
+	for global.Outer != nil {
+		global = global.Outer
+	}
+	symbol := Symbol{Name: name, Scope: GlobalScope, Index: global.numDefinitions, late: true}
+	global.store[name] = symbol
+	global.numDefinitions++
+	return symbol
+}
+
+// Defines a builtin function with its index in the builtin registry, Builtins are defined in the outermost
+// table and are never captured as free variables.
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Scope: BuiltinScope, Index: index}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Defines the name of the function the table belongs to, The function can then refer to itself without
+// capturing the binding it is assigned to, which makes recursive closures possible.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Scope: FunctionScope, Index: 0}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Resolves the identifier and returns back false if it was never defined, Locals of enclosing functions are
+// turned into free symbols of this table.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if ok || s.Outer == nil {
+		return symbol, ok
+	}
+	symbol, ok = s.Outer.Resolve(name)
+	// Blocks belong to the same function as the enclosing table, So nothing has to be captured
+	if !ok || s.block {
+		return symbol, ok
+	}
+	if (symbol.Scope == GlobalScope && !symbol.block) || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+	return s.defineFree(symbol), true
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+	symbol := Symbol{Name: original.Name, Scope: FreeScope, Index: len(s.FreeSymbols) - 1, Constant: original.Constant,
+		uninitialized: original.uninitialized}
+	s.store[original.Name] = symbol
+	return symbol
+}
@@ -0,0 +1,195 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+	}
+	global := NewSymbolTable()
+	a := global.Define("a")
+	if a != expected["a"] {
+		t.Errorf("expected a=%+v, got=%+v", expected["a"], a)
+	}
+	b := global.Define("b")
+	if b != expected["b"] {
+		t.Errorf("expected b=%+v, got=%+v", expected["b"], b)
+	}
+	redefined := global.Define("a")
+	if redefined != expected["a"] {
+		t.Errorf("redefining a should reuse %+v, got=%+v", expected["a"], redefined)
+	}
+}
+
+func TestResolveGlobal(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+	}
+	for _, sym := range expected {
+		result, ok := global.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+	if _, ok := global.Resolve("c"); ok {
+		t.Errorf("undefined name c should not resolve")
+	}
+}
+
+func TestResolveLocalAndFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+
+	tests := []struct {
+		table               *SymbolTable
+		expectedSymbols     []Symbol
+		expectedFreeSymbols []Symbol
+	}{
+		{
+			firstLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "c", Scope: LocalScope, Index: 0},
+			},
+			[]Symbol{},
+		},
+		{
+			secondLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "c", Scope: FreeScope, Index: 0},
+				{Name: "e", Scope: LocalScope, Index: 0},
+			},
+			[]Symbol{
+				{Name: "c", Scope: LocalScope, Index: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, sym := range tt.expectedSymbols {
+			result, ok := tt.table.Resolve(sym.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", sym.Name)
+				continue
+			}
+			if result != sym {
+				t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+			}
+		}
+		if len(tt.table.FreeSymbols) != len(tt.expectedFreeSymbols) {
+			t.Errorf("wrong number of free symbols. got=%d, want=%d", len(tt.table.FreeSymbols), len(tt.expectedFreeSymbols))
+			continue
+		}
+		for i, sym := range tt.expectedFreeSymbols {
+			if tt.table.FreeSymbols[i] != sym {
+				t.Errorf("wrong free symbol. got=%+v, want=%+v", tt.table.FreeSymbols[i], sym)
+			}
+		}
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestDefineResolveBuiltins(t *testing.T) {
+	global := NewSymbolTable()
+	firstLocal := NewEnclosedSymbolTable(global)
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	expected := []Symbol{
+		{Name: "a", Scope: BuiltinScope, Index: 0},
+		{Name: "c", Scope: BuiltinScope, Index: 1},
+	}
+	for i, sym := range expected {
+		global.DefineBuiltin(i, sym.Name)
+	}
+	for _, table := range []*SymbolTable{global, firstLocal, secondLocal} {
+		for _, sym := range expected {
+			result, ok := table.Resolve(sym.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", sym.Name)
+				continue
+			}
+			if result != sym {
+				t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+			}
+		}
+		if len(table.FreeSymbols) != 0 {
+			t.Errorf("builtins should not be free symbols. got=%+v", table.FreeSymbols)
+		}
+	}
+}
+
+func TestBlockSymbolTables(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	block := NewBlockSymbolTable(global)
+	shadow := block.Define("a")
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 1, block: true}
+	if shadow != expected {
+		t.Errorf("expected block symbol %+v, got=%+v", expected, shadow)
+	}
+	if global.numDefinitions != 2 {
+		t.Errorf("block names should get global slots, got %d definitions", global.numDefinitions)
+	}
+	if result, _ := global.Resolve("a"); result.Index != 0 {
+		t.Errorf("global a should not be shadowed outside of the block, got=%+v", result)
+	}
+
+	function := NewEnclosedSymbolTable(block)
+	inner := NewBlockSymbolTable(function)
+	local := inner.Define("b")
+	if local != (Symbol{Name: "b", Scope: LocalScope, Index: 0}) {
+		t.Errorf("block inside of a function should define locals, got=%+v", local)
+	}
+	if function.numDefinitions != 1 {
+		t.Errorf("block names should get slots of the function, got %d definitions", function.numDefinitions)
+	}
+	// The global of the block is captured by the function, Resolving through the inner block does not add
+	// a second free symbol
+	free, ok := inner.Resolve("a")
+	if !ok || free != (Symbol{Name: "a", Scope: FreeScope, Index: 0}) {
+		t.Errorf("block global should be captured as free symbol, got=%+v", free)
+	}
+	if len(function.FreeSymbols) != 1 || len(inner.FreeSymbols) != 0 {
+		t.Errorf("wrong free symbols. function=%+v block=%+v", function.FreeSymbols, inner.FreeSymbols)
+	}
+}
+
+func TestDefineConstant(t *testing.T) {
+	global := NewSymbolTable()
+	c := global.DefineConstant("c")
+	if !c.Constant {
+		t.Errorf("constant symbol expected, got=%+v", c)
+	}
+	free, _ := NewEnclosedSymbolTable(NewBlockSymbolTable(global)).Resolve("c")
+	if free.Scope != GlobalScope || !free.Constant {
+		t.Errorf("constant should resolve as constant global, got=%+v", free)
+	}
+	// Declaring the name again with let in a later input of the REPL makes it assignable
+	if redefined := global.Define("c"); redefined.Constant || redefined.Index != c.Index {
+		t.Errorf("redefined symbol should reuse the slot and not be constant, got=%+v", redefined)
+	}
+}
@@ -0,0 +1,143 @@
+// This package runs BJS programs through both engines, the tree walking evaluator and the compiler together
+// with the virtual machine, and records what every program did in a form that can be compared. The tests of
+// this package run the corpus in testdata through both engines, so the engines can not drift apart silently.
+package conformance
+
+import (
+	"bytes"
+	"compiler/ast"
+	"compiler/compiler"
+	"compiler/diagnostic"
+	"compiler/evaluator"
+	"compiler/lexer"
+	"compiler/object"
+	"compiler/parser"
+	"compiler/virtualmachine"
+	"strings"
+)
+
+// Result is what a program did when it was run by one of the engines
+type Result struct {
+	Output string // Everything the program printed
+	Value  string // Inspected value of the last expression, Empty when the program ends without a value
+	Error  string // Message of the error that stopped the program, Empty when the program succeeded
+	Stack  string // Stack of the error when it left a function, Formatted like the CLI prints it
+	// Set when the compiler rejected the program before it ran, Such errors have no stack
+	CompileError bool
+}
+
+// Returns back the result in the format of the expected output files, The printed output comes first and is
+// followed by the value as `=> value` or the error as `error: message` along with its stack.
+func (r Result) String() string {
+	var out bytes.Buffer
+	out.WriteString(r.Output)
+	if r.Error != "" {
+		out.WriteString("error: " + r.Error + "\n")
+		if r.Stack != "" {
+			out.WriteString(r.Stack + "\n")
+		}
+	} else if r.Value != "" {
+		out.WriteString("=> " + r.Value + "\n")
+	}
+	return out.String()
+}
+
+// Runs the source with the tree walking evaluator
+func Eval(source string) Result {
+	program, result, ok := parse(source)
+	if !ok {
+		return result
+	}
+	restore := captureOutput()
+	evaluated := evaluator.Eval(program, object.NewEnviornment())
+	result.Output = restore()
+	if errObj, ok := evaluated.(*object.Error); ok {
+		result.Error = errObj.Message
+		result.Stack = stackOf(errObj.Trace())
+	} else if evaluated != nil {
+		result.Value = evaluated.Inspect()
+	}
+	return result
+}
+
+// Runs the source with the compiler and the virtual machine
+func VM(source string) Result {
+	program, result, ok := parse(source)
+	if !ok {
+		return result
+	}
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		result.Error = errorMessage(err)
+		result.CompileError = true
+		return result
+	}
+	machine := virtualmachine.New(comp.ByteCode())
+	restore := captureOutput()
+	err := machine.Run()
+	result.Output = restore()
+	if err != nil {
+		result.Error = errorMessage(err)
+		if runtimeErr, ok := err.(*virtualmachine.RuntimeError); ok {
+			result.Stack = stackOf(runtimeErr.Stack)
+		}
+		return result
+	}
+	// Let statements and loops leave no value behind, Same as in the evaluator
+	if !program.EndsWithValue() {
+		return result
+	}
+	if value := machine.LastPoppedStackElem(); value != nil {
+		result.Value = value.Inspect()
+	}
+	return result
+}
+
+// Parses the source and expands its macros, Parse and expansion errors are shared by both engines and are
+// reported as the error of the result
+func parse(source string) (*ast.Program, Result, bool) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		messages := []string{}
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return nil, Result{Error: strings.Join(messages, "; ")}, false
+	}
+	macros := object.NewEnviornment()
+	evaluator.DefineMacros(program, macros)
+	program, err := evaluator.ExpandMacros(program, macros)
+	if err != nil {
+		return nil, Result{Error: errorMessage(err)}, false
+	}
+	return program, Result{}, true
+}
+
+// Compiler errors are diagnostics which carry the position, Only the message is compared with the evaluator
+func errorMessage(err error) string {
+	if d, ok := err.(*diagnostic.Diagnostic); ok {
+		return d.Message
+	}
+	return err.Error()
+}
+
+// Only errors which left a function have a stack, Like the CLI prints them
+func stackOf(stack []diagnostic.Frame) string {
+	if len(stack) < 2 {
+		return ""
+	}
+	return diagnostic.Trace(stack)
+}
+
+// Redirects the output of the builtins into a buffer, The returned function restores the output and returns
+// back what was printed.
+func captureOutput() func() string {
+	var out bytes.Buffer
+	previous := object.Stdout
+	object.Stdout = &out
+	return func() string {
+		object.Stdout = previous
+		return out.String()
+	}
+}
@@ -0,0 +1,74 @@
+package conformance
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Regenerates the expected output files from the evaluator, Run with `go test ./conformance -update`
+var update = flag.Bool("update", false, "rewrite the expected output files in testdata")
+
+// Every .bjs file in testdata is run through both engines, The results have to match each other and the
+// expected output in the .out file next to the program. New cases are added by dropping in a new pair of files.
+func TestCorpus(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "*.bjs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(programs) == 0 {
+		t.Fatal("no programs found in testdata")
+	}
+	for _, path := range programs {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".bjs"), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			evaluated := Eval(string(source))
+			compiled := VM(string(source))
+			// Errors the compiler finds before the program runs have no stack, The evaluator only finds them when
+			// it runs into them
+			if compiled.CompileError {
+				evaluated.Stack = ""
+			}
+			if evaluated.String() != compiled.String() {
+				t.Errorf("engines disagree\neval:\n%s\nvm:\n%s", evaluated, compiled)
+			}
+			golden := strings.TrimSuffix(path, ".bjs") + ".out"
+			if *update {
+				if err := os.WriteFile(golden, []byte(evaluated.String()), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing expected output, run the tests with -update to create it: %s", err)
+			}
+			if evaluated.String() != string(expected) {
+				t.Errorf("eval result does not match %s\nwant:\n%s\ngot:\n%s", golden, expected, evaluated)
+			}
+			if compiled.String() != string(expected) {
+				t.Errorf("vm result does not match %s\nwant:\n%s\ngot:\n%s", golden, expected, compiled)
+			}
+		})
+	}
+}
+
+// Only errors of the compiler may lack the stack of the evaluator, Runtime errors of both engines have one
+func TestStacks(t *testing.T) {
+	source := "let f = fn() { 1 + true };\nf()"
+	evaluated, compiled := Eval(source), VM(source)
+	if compiled.CompileError || compiled.Stack == "" || compiled.Stack != evaluated.Stack {
+		t.Errorf("wrong stacks\neval:\n%s\nvm:\n%s", evaluated.Stack, compiled.Stack)
+	}
+	if compiled := VM("let a = 1; a()"); compiled.CompileError || compiled.Error == "" {
+		t.Errorf("runtime error reported as compile error: %s", compiled.Error)
+	}
+	if compiled := VM("x = 1"); !compiled.CompileError {
+		t.Errorf("compile error not reported as such: %s", compiled.Error)
+	}
+}
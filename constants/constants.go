@@ -7,29 +7,51 @@ const (
 	IDENTFIER = "IDENTIFIER"
 	SEPERATOR = "SEPERATOR"
 	PROMPT    = ">>"
+	// Prompt shown while the REPL waits for the rest of an incomplete input
+	CONTINUATION_PROMPT = ".."
 )
 
 const (
 	INTEGER_OBJECT      = "INTEGER"
+	FLOAT_OBJECT        = "FLOAT"
 	BOOLEAN_OBJECT      = "BOOLEAN"
 	NULL_OBJECT         = "NULL"
 	RETURN_VALUE_OBJECT = "RETURN_VALUE"
 	ERROR_OBJECT        = "ERROR"
 	FUNCTION_OBJ        = "FUNCTION"
+	COMPILED_FUNCTION   = "COMPILED_FUNCTION"
 	STRING_OBJECT       = "STRING"
 	BUILTIN_OBJECT      = "BUILTIN"
 	ARRAY_OBJECT        = "ARRAY"
 	HASH_OBJECT         = "HASH"
+	BREAK_OBJECT        = "BREAK"
+	CONTINUE_OBJECT     = "CONTINUE"
+	ITERATOR_OBJECT     = "ITERATOR"
+	CELL_OBJECT         = "CELL"
+	QUOTE_OBJECT        = "QUOTE"
+	MACRO_OBJECT        = "MACRO"
+	EXCEPTION_OBJECT    = "EXCEPTION"
+	BIGINT_OBJECT       = "BIGINT"
+	UNINITIALIZED_OBJ   = "UNINITIALIZED"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN
+	LOGICALOR
+	LOGICALAND
+	BITOR
+	BITXOR
+	BITAND
 	EQUALS
 	LESSGREATER
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	EXPONENT // Above prefix so that -2 ** 2 is -(2 ** 2)
+	POSTFIX
 	CALL
 	INDEX
 )
@@ -41,3 +63,7 @@ const (
  > ^ <
 `
 )
+
+const (
+	VERSION = "0.1.0"
+)
@@ -0,0 +1,126 @@
+// This package formats errors found in BJS source code, Every diagnostic carries the position where the
+// problem was found so that the parser, the evaluator and the compiler all report errors in the same way:
+//
+//	file.bjs:12:7: message
+//	    let x = 5 + true;
+//	                ^
+package diagnostic
+
+import (
+	"compiler/lexer"
+	"compiler/token"
+	"fmt"
+	"strings"
+)
+
+// Diagnostic is an error message attached to a position in the source
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+	Stack   []Frame // Calls the error unwound through with the innermost first, Only runtime errors have them
+}
+
+// Frame is a call that was running when a runtime error happened, Pos is where the function was when the
+// error left it. Function is empty for the top level code and <anonymous> for functions without a name.
+type Frame struct {
+	Function string
+	Pos      token.Position
+}
+
+func (f Frame) String() string {
+	if f.Function == "" {
+		return "at " + f.Pos.String()
+	}
+	return "at " + f.Function + " (" + f.Pos.String() + ")"
+}
+
+// Upper limit on the frames of a printed stack, Deep recursion would otherwise print a frame for every call
+const MaxTraceFrames = 20
+
+// Creates a new diagnostic, The message is formatted with fmt.Sprintf
+func New(pos token.Position, format string, a ...interface{}) *Diagnostic {
+	return &Diagnostic{Pos: pos, Message: fmt.Sprintf(format, a...)}
+}
+
+// Error returns back the message prefixed by the position, Diagnostics can be returned as errors.
+func (d *Diagnostic) Error() string {
+	if !d.Pos.IsValid() && d.Pos.File == "" {
+		return d.Message
+	}
+	return d.Pos.String() + ": " + d.Message
+}
+
+// Render returns back the error message followed by the offending source line and a caret line which
+// underlines the token at the position. Only the message is returned back when the position is unknown.
+// The stack is printed after them when the error unwound through a function call.
+func (d *Diagnostic) Render(source string) string {
+	rendered := d.Error()
+	if excerpt := Excerpt(source, d.Pos); excerpt != "" {
+		rendered += "\n" + excerpt
+	}
+	if len(d.Stack) > 1 {
+		rendered += "\n" + Trace(d.Stack)
+	}
+	return rendered
+}
+
+// Trace formats the frames with one frame per line, the innermost first. Frames beyond MaxTraceFrames are
+// left out and only counted.
+func Trace(stack []Frame) string {
+	lines := []string{}
+	for i, frame := range stack {
+		if i == MaxTraceFrames {
+			lines = append(lines, fmt.Sprintf("    ... %d more frames", len(stack)-i))
+			break
+		}
+		lines = append(lines, "    "+frame.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Excerpt returns back the source line of the position and a caret line underneath it, The width of the
+// underline is the length of the token starting at the position.
+func Excerpt(source string, pos token.Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	lines := strings.Split(source, "\n")
+	if pos.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	column := pos.Column
+	if column < 1 || column > len(line)+1 {
+		return ""
+	}
+	// Tabs are kept in the padding so that the caret lines up with the source line in the terminal
+	var padding strings.Builder
+	for _, ch := range line[:column-1] {
+		if ch == '\t' {
+			padding.WriteByte('\t')
+		} else {
+			padding.WriteByte(' ')
+		}
+	}
+	width := tokenWidth(line[column-1:])
+	return "    " + line + "\n    " + padding.String() + strings.Repeat("^", width)
+}
+
+// Lexes the start of the text to find out how many characters the token at the position spans
+func tokenWidth(text string) int {
+	tok := lexer.New(text).NextToken()
+	if tok.Pos.Offset != 0 {
+		return 1
+	}
+	width := len(tok.Literal)
+	if tok.Type == token.STRING {
+		width += 2
+	}
+	if width < 1 {
+		return 1
+	}
+	if width > len(text) && len(text) > 0 {
+		return len(text)
+	}
+	return width
+}
@@ -0,0 +1,83 @@
+package diagnostic
+
+import (
+	"compiler/token"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	source := "let a = 1;\n\tlet b = a + \"text\";\n"
+	tests := []struct {
+		pos      token.Position
+		message  string
+		expected string
+	}{
+		{
+			token.Position{File: "main.bjs", Line: 1, Column: 5, Offset: 4},
+			"unused variable",
+			"main.bjs:1:5: unused variable\n    let a = 1;\n        ^",
+		},
+		{
+			token.Position{File: "main.bjs", Line: 2, Column: 14, Offset: 24},
+			"type mismatch: INTEGER + STRING",
+			"main.bjs:2:14: type mismatch: INTEGER + STRING\n    \tlet b = a + \"text\";\n    \t            ^^^^^^",
+		},
+		{
+			token.Position{Line: 1, Column: 11, Offset: 10},
+			"unexpected end",
+			"1:11: unexpected end\n    let a = 1;\n              ^",
+		},
+		{
+			token.Position{File: "main.bjs"},
+			"stack overflow",
+			"main.bjs: stack overflow",
+		},
+		{
+			token.Position{},
+			"stack overflow",
+			"stack overflow",
+		},
+	}
+	for _, tt := range tests {
+		rendered := New(tt.pos, "%s", tt.message).Render(source)
+		if rendered != tt.expected {
+			t.Errorf("wrong rendering.\nwant=%q\ngot =%q", tt.expected, rendered)
+		}
+	}
+}
+
+func TestRenderStack(t *testing.T) {
+	source := "let f = fn(x) { x + true };\nf(1);\n"
+	at := func(line, column int) token.Position {
+		return token.Position{File: "main.bjs", Line: line, Column: column}
+	}
+	d := &Diagnostic{Pos: at(1, 19), Message: "type mismatch: INTEGER + BOOLEAN", Stack: []Frame{
+		{Function: "f", Pos: at(1, 19)},
+		{Pos: at(2, 2)},
+	}}
+	expected := "main.bjs:1:19: type mismatch: INTEGER + BOOLEAN\n" +
+		"    let f = fn(x) { x + true };\n" +
+		"                      ^\n" +
+		"    at f (main.bjs:1:19)\n" +
+		"    at main.bjs:2:2"
+	if rendered := d.Render(source); rendered != expected {
+		t.Errorf("wrong rendering.\nwant=%q\ngot =%q", expected, rendered)
+	}
+	// A single frame only repeats the position of the message
+	d.Stack = d.Stack[1:]
+	if rendered := d.Render(source); rendered != "main.bjs:1:19: type mismatch: INTEGER + BOOLEAN\n    let f = fn(x) { x + true };\n                      ^" {
+		t.Errorf("single frame is rendered. got=%q", rendered)
+	}
+}
+
+func TestTraceLimit(t *testing.T) {
+	stack := make([]Frame, MaxTraceFrames+5)
+	for i := range stack {
+		stack[i] = Frame{Function: "<anonymous>", Pos: token.Position{Line: 1, Column: 1}}
+	}
+	lines := strings.Split(Trace(stack), "\n")
+	if len(lines) != MaxTraceFrames+1 || lines[MaxTraceFrames] != "    ... 5 more frames" {
+		t.Errorf("wrong trace. got=%q", lines)
+	}
+}
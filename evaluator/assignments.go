@@ -0,0 +1,89 @@
+package evaluator
+
+import (
+	"compiler/ast"
+	"compiler/object"
+)
+
+// Compound assignments and updates apply the arithmetic operator to the current value of the target
+var assignmentOperators = map[string]string{
+	"+=": "+",
+	"-=": "-",
+	"*=": "*",
+	"/=": "/",
+	"++": "+",
+	"--": "-",
+}
+
+// The current value of a compound assignment is read before the value is evaluated, Same as in JavaScript
+func evalAssignExpression(node *ast.AssignExpression, env *object.Enviornment) object.Object {
+	return evalAssignment(node.Target, env, func(current object.Object) object.Object {
+		value := Eval(node.Value, env)
+		if isError(value) || node.Operator == "=" {
+			return value
+		}
+		return evalInfixExpression(assignmentOperators[node.Operator], current, value)
+	}, node.Operator != "=")
+}
+
+func evalUpdateExpression(node *ast.UpdateExpression, env *object.Enviornment) object.Object {
+	var old object.Object
+	updated := evalAssignment(node.Target, env, func(current object.Object) object.Object {
+		old = current
+		return evalInfixExpression(assignmentOperators[node.Operator], current, &object.Integer{Value: 1})
+	}, true)
+	if isError(updated) || node.Prefix {
+		return updated
+	}
+	return old
+}
+
+// Stores the value computed from the current value of the target, The target is only read when needed.
+// The left side and the index of an index expression are evaluated once.
+func evalAssignment(target ast.Expression, env *object.Enviornment, compute func(object.Object) object.Object, readCurrent bool) object.Object {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			err := newReferenceError("assignment to undeclared identifier: %s", target.Value)
+			err.Pos = target.Pos()
+			return err
+		}
+		if env.IsConst(target.Value) {
+			err := newTypeError("assignment to constant variable: %s", target.Value)
+			err.Pos = target.Pos()
+			return err
+		}
+		value := compute(current)
+		if isError(value) {
+			return value
+		}
+		env.Assign(target.Value, value)
+		return value
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		var current object.Object
+		if readCurrent {
+			current = evalIndexExpression(left, index)
+			if isError(current) {
+				return current
+			}
+		}
+		value := compute(current)
+		if isError(value) {
+			return value
+		}
+		if err := object.SetIndex(left, index, value); err != nil {
+			return newErrorFrom(err)
+		}
+		return value
+	}
+	return newError("invalid assignment target %s", target.String())
+}
@@ -1,108 +0,0 @@
-package evaluator
-
-import (
-	"compiler/constants"
-	"compiler/object"
-	"fmt"
-)
-
-// Bultin functions to check on, Make and add more builtin functions here.
-// Retuns back the pointer to builtin object.
-// TODO: There is repetative checks for single array fn, Make sure you put them in single array checker code
-// This will make things simpler
-var builtins = map[string]*object.Builtin{
-	"len": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			switch arg := args[0].(type) {
-			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
-			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elements))}
-			default:
-				return newError("argument to `len` not supported, got %s", args[0].Type())
-			}
-		},
-	},
-	// First function returns back the first element in an array
-	"first": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != constants.ARRAY_OBJECT {
-				return newError("argument to first is invalid. got=%s", args[0].Type())
-			}
-			arr := args[0].(*object.Array)
-			if len(arr.Elements) > 0 {
-				return arr.Elements[0]
-			}
-			return NULL
-		},
-	},
-	// Gets the last element in an array and then push it to commandline for interpreter
-	"last": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != constants.ARRAY_OBJECT {
-				return newError("argument to first is invalid. got=%s", args[0].Type())
-			}
-			arr := args[0].(*object.Array)
-			len := len(arr.Elements)
-			if len > 0 {
-				return arr.Elements[len-1]
-			}
-			return NULL
-		},
-	},
-	// Rest function for array returns you back the array popping the first element from array.
-	"rest": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != constants.ARRAY_OBJECT {
-				return newError("argument to first is invalid. got=%s", args[0].Type())
-			}
-			arr := args[0].(*object.Array)
-			length := len(arr.Elements)
-			if length > 0 {
-				newElements := make([]object.Object, length-1)
-				copy(newElements, arr.Elements[1:length])
-				return &object.Array{Elements: newElements}
-			}
-			return NULL
-		},
-	},
-	// Push function pushes things to array
-	"push": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != constants.ARRAY_OBJECT {
-				return newError("argument to first is invalid. got=%s", args[0].Type())
-			}
-			arr := args[0].(*object.Array)
-			length := len(arr.Elements)
-			newElements := make([]object.Object, length+1)
-			copy(newElements, arr.Elements)
-			newElements[length] = args[1]
-			return &object.Array{Elements: newElements}
-		},
-	},
-	// Prints string to console
-	// FIX: Always returns a null after printing strings to console.
-	"prints": {
-		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
-			}
-			return NULL
-		},
-	},
-}
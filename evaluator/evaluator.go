@@ -4,7 +4,10 @@ import (
 	"compiler/ast"
 	"compiler/constants"
 	"compiler/object"
+	"compiler/token"
 	"fmt"
+	"math"
+	"math/big"
 )
 
 // This is predeclared variable block for memory allocation for true, false and null objects in memory
@@ -13,14 +16,30 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// Names of the functions which are being evaluated with the innermost last, Its length is the depth of the
+// calls and the last name is the function a catch block belongs to
+var calls []string
+
 // Function evaluator mrecieves ast.Node and stores into memory for representation
 // Eval returns object which is stored into memory which is represented in golang struct
 // For debugging purpose the struct takes more memory in ram.
 // This will be fixed in upcoming versions of BJS, Reference to objects are provided in this case which contains
 // debugging info as well.
+// Errors are stamped with the position of the innermost node that produced them.
 func Eval(node ast.Node, env *object.Enviornment) object.Object {
+	result := evalNode(node, env)
+	if err, ok := result.(*object.Error); ok && node != nil && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Enviornment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
@@ -28,6 +47,10 @@ func Eval(node ast.Node, env *object.Enviornment) object.Object {
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	case *ast.BigIntLiteral:
+		return &object.BigInt{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -39,7 +62,17 @@ func Eval(node ast.Node, env *object.Enviornment) object.Object {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.IsConst() {
+			env.SetConst(node.Name.Value, val)
+		} else {
+			env.Set(node.Name.Value, val)
+		}
+	case *ast.FunctionDeclaration:
+		return nil
+	case *ast.MacroLiteral:
+		err := newError("macros can only be bound with a top level let statement")
+		err.Pos = node.Pos()
+		return err
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.PrefixExpression:
@@ -65,6 +98,9 @@ func Eval(node ast.Node, env *object.Enviornment) object.Object {
 		}
 		return evalIndexExpression(left, index)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -81,8 +117,11 @@ func Eval(node ast.Node, env *object.Enviornment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Env: env, Body: body, Name: node.Name}
 	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			return quote(node, env)
+		}
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -91,13 +130,31 @@ func Eval(node ast.Node, env *object.Enviornment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return applyFunction(function, args, node.Pos())
 	case *ast.ReturnStatement:
 		value := Eval(node.ReturnValue, env)
 		if isError(value) {
 			return value
 		}
 		return &object.ReturnValue{Value: value}
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.ForEachStatement:
+		return evalForEachStatement(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.UpdateExpression:
+		return evalUpdateExpression(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
 	}
 	return nil
 }
@@ -112,8 +169,14 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == constants.HASH_OBJECT:
 		return evalHashIndexExpression(left, index)
+	case object.HasMembers(left) && index.Type() == constants.STRING_OBJECT:
+		member, err := object.GetMember(left, index.(*object.String).Value)
+		if err != nil {
+			return newErrorFrom(err)
+		}
+		return member
 	default:
-		return newError("index operator has wrong type that is not supported yet %s", left.Type())
+		return newTypeError("index operator has wrong type that is not supported yet %s", left.Type())
 	}
 }
 
@@ -122,7 +185,7 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newTypeError("unusable as hash key: %s", index.Type())
 	}
 	pair, ok := hashObject.Pairs[key.HashKey()]
 	if !ok {
@@ -159,7 +222,7 @@ func evaluateHashLiteral(node *ast.HashLiteral, env *object.Enviornment) object.
 		// Check if the key implemnents hashable function, Eg; Keys can be int, bool or string
 		hashkey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newTypeError("unusable as hash key: %s", key.Type())
 		}
 		value := Eval(valueNode, env)
 		if isError(value) {
@@ -172,16 +235,40 @@ func evaluateHashLiteral(node *ast.HashLiteral, env *object.Enviornment) object.
 	return &object.Hash{Pairs: pairs}
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// Calls the function with the arguments, call is the position of the call which continues the stack of errors
+// that leave the function
+func applyFunction(fn object.Object, args []object.Object, call token.Position) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newTypeError("wrong number of arguments: want=%d, got=%d", len(fn.Parameters), len(args))
+		}
+		// Deeper recursion is a catchable error instead of overflowing the stack of Go
+		if len(calls) >= object.MaxCallDepth {
+			return newRangeError("stack overflow")
+		}
+		calls = append(calls, object.FunctionName(fn.Name))
+		defer func() { calls = calls[:len(calls)-1] }()
 		extendedEnv := extendedFunctionEnviornment(fn, args)
 		evaluated := Eval(fn.Body, extendedEnv)
+		// Errors of the body get the frame of the function, The stack continues at the call
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Unwind(object.FunctionName(fn.Name), call)
+			return err
+		}
+		// Bodies which do not end with an expression return back null
+		if evaluated == nil {
+			return NULL
+		}
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		result := fn.Fn(args...)
+		if result == nil {
+			return NULL
+		}
+		return result
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newTypeError("not a function: %s", fn.Type())
 	}
 }
 
@@ -214,6 +301,7 @@ func evalExpressions(exps []ast.Expression, env *object.Enviornment) []object.Ob
 
 func evalProgram(node *ast.Program, env *object.Enviornment) object.Object {
 	var result object.Object
+	hoistFunctions(node.Statements, env)
 	for _, statement := range node.Statements {
 		result = Eval(statement, env)
 		switch result := result.(type) {
@@ -231,20 +319,20 @@ func evalIdentifier(node *ast.Identifier, env *object.Enviornment) object.Object
 	if ok {
 		return val
 	}
-	builtin, ok := builtins[node.Value]
-	if ok {
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
 		return builtin
 	}
-	return newError("identifier not found: " + node.Value)
+	return newReferenceError("identifier not found: " + node.Value)
 }
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Enviornment) object.Object {
 	var result object.Object
+	hoistFunctions(block.Statements, env)
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == constants.RETURN_VALUE_OBJECT || rt == constants.ERROR_OBJECT {
+			if rt == constants.RETURN_VALUE_OBJECT || rt == constants.ERROR_OBJECT || rt == constants.BREAK_OBJECT || rt == constants.CONTINUE_OBJECT {
 				return result
 			}
 		}
@@ -252,15 +340,33 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Enviornment) obje
 	return result
 }
 
+// Function declarations are bound before the statements of their block run, So they can be called before
+// the declaration and can call each other. The declaration itself does nothing when it is reached.
+func hoistFunctions(statements []ast.Statement, env *object.Enviornment) {
+	for _, statement := range statements {
+		if decl, ok := statement.(*ast.FunctionDeclaration); ok {
+			env.Set(decl.Name.Value, &object.Function{Parameters: decl.Function.Parameters, Env: env, Body: decl.Function.Body, Name: decl.Name.Value})
+		}
+	}
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Enviornment) object.Object {
 	condition := Eval(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+	// Names declared in a branch are only visible inside of it
+	var result object.Object
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		result = Eval(ie.Consequence, object.NewEnclosedEnviornment(env))
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
-	} else {
+		result = Eval(ie.Alternative, object.NewEnclosedEnviornment(env))
+	}
+	// Branches which do not end with an expression, like a branch that only breaks out of a loop, are null
+	if result == nil {
 		return NULL
 	}
+	return result
 }
 
 func isTruthy(obj object.Object) bool {
@@ -284,22 +390,42 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// Logical operators short circuit, The right side is only evaluated when the left side does not decide the result.
+// Like JavaScript the result is one of the operand values rather than a boolean.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Enviornment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	if node.Operator == "&&" && !isTruthy(left) {
+		return left
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return left
+	}
+	return Eval(node.Right, env)
+}
+
 // Eval Inflix expression returns back the infix expression, It checks if both of the right and left nodes of the ast
 // are integer, If so then it returns back the integer object back
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == constants.INTEGER_OBJECT && right.Type() == constants.INTEGER_OBJECT:
 		return evalIntegerInflixExpression(operator, left, right)
+	case object.IsInteger(left) && object.IsInteger(right):
+		return evalBigIntInfixExpression(operator, left, right)
+	case object.IsNumber(left) && object.IsNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBooleanToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBooleanToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case left.Type() == constants.STRING_OBJECT && right.Type() == constants.STRING_OBJECT:
 		return evalStringInfixExpression(operator, left, right)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -308,24 +434,89 @@ func evalIntegerInflixExpression(operator string, left, right object.Object) obj
 	leftValue := left.(*object.Integer).Value
 	rightValue := right.(*object.Integer).Value
 	switch operator {
+	case "+", "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>", ">>>":
+		result, err := object.IntegerArithmetic(operator, leftValue, rightValue)
+		if err != nil {
+			return newErrorFrom(err)
+		}
+		return result
+	case "<":
+		return nativeBooleanToBooleanObject(leftValue < rightValue)
+	case ">":
+		return nativeBooleanToBooleanObject(leftValue > rightValue)
+	case "<=":
+		return nativeBooleanToBooleanObject(leftValue <= rightValue)
+	case ">=":
+		return nativeBooleanToBooleanObject(leftValue >= rightValue)
+	case "==":
+		return nativeBooleanToBooleanObject(leftValue == rightValue)
+	case "!=":
+		return nativeBooleanToBooleanObject(leftValue != rightValue)
+	default:
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// Eval big integer infix expression is used when one of the operands is a big integer and the other one is
+// an integer or a big integer, Integers are promoted to big integers
+func evalBigIntInfixExpression(operator string, left, right object.Object) object.Object {
+	leftValue := object.ToBigInt(left)
+	rightValue := object.ToBigInt(right)
+	switch operator {
+	case "+", "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>":
+		result, err := object.BigArithmetic(operator, leftValue, rightValue)
+		if err != nil {
+			return newErrorFrom(err)
+		}
+		return result
+	case "<":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) < 0)
+	case ">":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) > 0)
+	case "<=":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) <= 0)
+	case ">=":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) >= 0)
+	case "==":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) == 0)
+	case "!=":
+		return nativeBooleanToBooleanObject(leftValue.Cmp(rightValue) != 0)
+	default:
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// Eval float infix expression is used when one of the operands is a float, The integer operand is promoted to float
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftValue := object.ToFloat(left)
+	rightValue := object.ToFloat(right)
+	switch operator {
 	case "+":
-		return &object.Integer{Value: leftValue + rightValue}
+		return &object.Float{Value: leftValue + rightValue}
 	case "-":
-		return &object.Integer{Value: leftValue - rightValue}
+		return &object.Float{Value: leftValue - rightValue}
 	case "*":
-		return &object.Integer{Value: leftValue * rightValue}
+		return &object.Float{Value: leftValue * rightValue}
 	case "/":
-		return &object.Integer{Value: leftValue / rightValue}
+		return &object.Float{Value: leftValue / rightValue}
+	case "%":
+		return &object.Float{Value: math.Mod(leftValue, rightValue)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftValue, rightValue)}
 	case "<":
 		return nativeBooleanToBooleanObject(leftValue < rightValue)
 	case ">":
 		return nativeBooleanToBooleanObject(leftValue > rightValue)
+	case "<=":
+		return nativeBooleanToBooleanObject(leftValue <= rightValue)
+	case ">=":
+		return nativeBooleanToBooleanObject(leftValue >= rightValue)
 	case "==":
 		return nativeBooleanToBooleanObject(leftValue == rightValue)
 	case "!=":
 		return nativeBooleanToBooleanObject(leftValue != rightValue)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -336,22 +527,45 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalBitwiseNotPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newTypeError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != constants.INTEGER_OBJECT {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		negated, err := object.NegateInteger(right.Value)
+		if err != nil {
+			return newErrorFrom(err)
+		}
+		return negated
+	case *object.BigInt:
+		return &object.BigInt{Value: new(big.Int).Neg(right.Value)}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newTypeError("unknown operator: -%s", right.Type())
+	}
+}
+
+// Inverts all of the bits, For big integers it is -x - 1 like for the integers
+func evalBitwiseNotPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInt:
+		return &object.BigInt{Value: new(big.Int).Not(right.Value)}
+	default:
+		return newTypeError("unknown operator: ~%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
@@ -369,7 +583,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	case FALSE:
 		return TRUE
 	case NULL:
-		return FALSE
+		return TRUE
 	default:
 		return FALSE
 	}
@@ -390,5 +604,22 @@ func nativeBooleanToBooleanObject(value bool) object.Object {
 // This function basically formats the string and returns back the error object address stored in memory.
 // Uses pointer to do the work.
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return &object.Error{Kind: object.ErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+func newTypeError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: object.TypeErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+func newRangeError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: object.RangeErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+func newReferenceError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: object.ReferenceErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+// Turns an error of the object package into an error object, The kind of the error is kept
+func newErrorFrom(err error) *object.Error {
+	return &object.Error{Kind: object.KindOf(err), Message: err.Error()}
 }
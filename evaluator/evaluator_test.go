@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"compiler/ast"
+	"compiler/diagnostic"
 	"compiler/lexer"
 	"compiler/object"
 	"compiler/parser"
@@ -165,6 +167,7 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{"!if (false) { 1 }", true},
 	}
 
 	for _, tt := range tests {
@@ -268,6 +271,10 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"let a = 10; a / (5 - 5);",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -420,6 +427,60 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let o = {"name": "bjs"}; o.name`, "bjs"},
+		{`let o = {"name": "bjs"}; o.missing`, nil},
+		{`let o = {"n": 1}; o.n = 5; o.n += 2; o.n++; o["n"]`, 8},
+		{`let o = {}; o.inner = {"x": 1}; o.inner.x`, 1},
+		{`let o = {"add": fn(a, b) { a + b }}; o.add(1, 2)`, 3},
+		{`"hello".length`, 5},
+		{`"Hello".toUpperCase() + "Hello".toLowerCase()`, "HELLOhello"},
+		{`"a,b,c".split(",")[1]`, "b"},
+		{`let a = [1]; a.push(2, 3)`, 3},
+		{`let a = [1, 2]; a.pop() + a.length`, 3},
+		{`[1, 2, 3].join("-")`, "1-2-3"},
+		{`[1, 2, 3, 4].slice(1, -1).length`, 2},
+		{`let a = [1]; let push = a.push; push(4); a[1]`, 4},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"[1].size", "ARRAY has no property size"},
+		{`"abc".push(1)`, "STRING has no property push"},
+		{"true.length", "index operator has wrong type that is not supported yet BOOLEAN"},
+		{`"abc".split()`, "wrong number of arguments. got=0, want=1"},
+	}
+	for _, tt := range errors {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -501,3 +562,637 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"5 + true;", 1, 3},
+		{"let x = 1;\nlet y = x * foobar;", 2, 13},
+		{"let f = fn(a) {\n  -a\n};\nf(true);", 2, 3},
+		{`len(1)`, 1, 4},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.Line != tt.expectedLine || errObj.Pos.Column != tt.expectedColumn {
+			t.Errorf("wrong error position for %q. expected=%d:%d, got=%s", tt.input, tt.expectedLine, tt.expectedColumn, errObj.Pos)
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"2.5 * 2", 5},
+		{"1 - 0.25", 0.75},
+		{"(1.5 + 2) * -2", -7},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestFloatComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1.0 == 1", true},
+		{"1 != 1.0", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"2.5 > 2.5", false},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestFloatHashKeys(t *testing.T) {
+	evaluated := testEval(`{1.5: "a", 2: "b"}[1.5]`)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "a" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float got back %T, %+v", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value got back %f, wanted %f", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 <= 2", true},
+		{"3 >= 4 || 4 >= 3", true},
+		{"1.5 <= 1", false},
+		{"2 >= 2.0", true},
+		{"1 && 2", 2},
+		{"false && 2", false},
+		{"1 || 2", 1},
+		{"false || 2", 2},
+		{"if (false) { 1 } || 5", 5},
+		// The right side must not be evaluated, otherwise the unknown identifier would produce an error
+		{"false && foobar", false},
+		{"true || foobar", true},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		}
+	}
+}
+
+func TestCallingFunctionsWithWrongArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { 1; }(1);", "wrong number of arguments: want=0, got=1"},
+		{"fn(a) { a; }();", "wrong number of arguments: want=1, got=0"},
+		{"fn(a, b) { a + b; }(1);", "wrong number of arguments: want=2, got=1"},
+		{"let a = 1; a(2);", "not a function: INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestFunctionsWithoutValue(t *testing.T) {
+	testNullObject(t, testEval("let f = fn() { let a = 1; }; f();"))
+	testNullObject(t, testEval("let f = fn() { }; f();"))
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while (i < 10) { i = i + 1; }; i", 10},
+		{"let sum = 0; for (let i = 1; i <= 4; i++) { sum = sum + i; }; sum", 10},
+		{"let i = 0; for (;;) { i = i + 1; if (i == 5) { break; } }; i", 5},
+		{"let sum = 0; for (let i = 0; i < 6; i++) { if (i == 2) { continue; } sum = sum + i; }; sum", 13},
+		{"let sum = 0; for (x of [1, 2, 3]) { sum = sum + x; }; sum", 6},
+		{"let sum = 0; for (i in [5, 5, 5]) { sum = sum + i; }; sum", 3},
+		{`let sum = 0; for (v of {"a": 1, "b": 2}) { sum = sum + v; }; sum`, 3},
+		{"let find = fn(xs) { for (x of xs) { if (x > 2) { return x; } } 0 }; find([1, 3, 5])", 3},
+		{"let count = 0; for (i of [1, 2]) { for (j of [1, 2, 3]) { if (j == 2) { break; } count = count + 1; } }; count", 2},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLoopValues(t *testing.T) {
+	if evaluated := testEval("while (false) { 1 }"); evaluated != nil {
+		t.Errorf("loop has a value. got=%T(%+v)", evaluated, evaluated)
+	}
+	evaluated := testEval(`let s = ""; for (k in {"b": 2, "a": 1}) { s = s + k; }; s`)
+	str, ok := evaluated.(*object.String)
+	if !ok || str.Value != "ab" {
+		t.Errorf("hash keys are not iterated in order. got=%T(%+v)", evaluated, evaluated)
+	}
+	errObj, ok := testEval("for (x of 5) { x }").(*object.Error)
+	if !ok || errObj.Message != "INTEGER is not iterable" {
+		t.Errorf("wrong error for iterating an integer. got=%+v", errObj)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 5; x", 5},
+		{"let x = 1; x += 2; x -= 1; x *= 10; x /= 4; x", 5},
+		{"let x = 1; let y = x = 7; y + x", 14},
+		{"let x = 1; x++ + x", 3},
+		{"let x = 1; ++x + x", 4},
+		{"let x = 5; x--; --x", 3},
+		{"let a = [1, 2, 3]; a[1] = 20; a[2] += 5; a[1] + a[2]", 28},
+		{"let a = [5]; a[0]++ + a[0]", 11},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 5; h["a"] + h["b"]`, 7},
+		{"let x = 1; let f = fn() { x = 10; }; f(); x", 10},
+		{"let x = 1; let f = fn() { let x = 2; x = 3; }; f(); x", 1},
+		{"let counter = fn() { let c = 0; fn() { c++; c } }; let next = counter(); next(); next(); next()", 3},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1;", "assignment to undeclared identifier: x"},
+		{"len += 1;", "assignment to undeclared identifier: len"},
+		{"let a = [1]; a[5] = 1;", "index out of range: 5"},
+		{"let h = {}; h[[1]] = 1;", "unusable as hash key: ARRAY"},
+		{`let s = "a"; s++;`, "type mismatch: STRING + INTEGER"},
+	}
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"const a = 1; a + 1", 2},
+		{"let a = 1; if (true) { let a = 2; a = 3; }; a", 1},
+		{"let a = 1; if (true) { let b = 2; a = a + b; }; a", 3},
+		{"let x = 0; for (let x = 5; x < 6; x++) { }; x", 0},
+		{"let fns = []; for (x of [1, 2, 3]) { fns = push(fns, fn() { x }) }; fns[0]() + fns[2]()", 4},
+		{"let fns = []; for (let i = 0; i < 3; i++) { let j = i; fns = push(fns, fn() { j }) }; fns[0]() + fns[1]()", 1},
+		{"let fns = []; for (let i = 0; i < 3; i++) { fns = push(fns, fn() { i }) }; fns[0]() + fns[2]()", 2},
+		{"let f = fn() { let fns = []; for (let i = 0; i < 3; i++) { fns = push(fns, fn() { i }) }; fns }; f()[1]()", 1},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"const a = 1; a = 2;", "assignment to constant variable: a"},
+		{"const a = [1]; a += 1;", "assignment to constant variable: a"},
+		{"for (const x of [1]) { x++ }", "assignment to constant variable: x"},
+		{"if (true) { let a = 1; }; a", "identifier not found: a"},
+	}
+	for _, tt := range errors {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+	// Elements of a constant array can still be changed, Only the binding is constant
+	testIntegerObject(t, testEval("const a = [1]; a[0] = 5; a[0]"), 5)
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"function add(a, b) { a + b } add(1, 2)", 3},
+		{"let r = twice(3); function twice(x) { x * 2 } r", 6},
+		{"function even(n) { if (n == 0) { return true } odd(n - 1) } function odd(n) { if (n == 0) { return false } even(n - 1) } even(10)", true},
+		{"function fact(n) { if (n < 2) { return 1 } n * fact(n - 1) } fact(5)", 120},
+		{"let f = fn() { function get() { x } let x = 1; x++; get() }; f()", 2},
+		{"let fns = []; for (x of [1, 2, 3]) { let v = x * 10; function get() { v } fns.push(get) }; fns[0]() + fns[2]()", 40},
+		{"if (true) { function inner() { 5 } inner() }", 5},
+		{"let anon = function(a) { a * 2 }; anon(4)", 8},
+		{"let add = (a, b) => a + b; add(2, 3)", 5},
+		{"let seven = () => { 7 }; seven()", 7},
+		{"let adder = x => y => x + y; adder(1)(2)", 3},
+		{"function f() { 1 }", nil},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			if evaluated != nil && evaluated != NULL {
+				t.Errorf("expected no value for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote("a" + "b"))`, `ab`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+		{`quote(f(unquote(1 + 1)))`, `f(2)`},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Errorf("expected *object.Quote for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if quote.Node.String() != tt.expected {
+			t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), tt.expected)
+		}
+	}
+}
+
+func testMacroProgram(input string) *ast.Program {
+	return parser.New(lexer.New(input)).ParseProgram()
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let add = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+	env := object.NewEnviornment()
+	program := testMacroProgram(input)
+	DefineMacros(program, env)
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in enviornment")
+	}
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+	if len(macro.Parameters) != 2 || macro.Body.String() != "(x + y)" {
+		t.Errorf("wrong macro. got=%s", macro.Inspect())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let infixExpression = macro() { quote(1 + 2); }; infixExpression();`,
+			`(1 + 2)`,
+		},
+		{
+			`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5);`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`let unless = macro(cond, yes, no) { quote(if (!(unquote(cond))) { unquote(yes); } else { unquote(no); }); };
+			unless(10 > 5, puts("not greater"), puts("greater"));`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+		{
+			`let sq = macro(x) { quote(unquote(x) * unquote(x)) }; let f = fn(n) { sq(n + 1) }; sq(sq(2))`,
+			`let f = fn(n) { ((n + 1) * (n + 1)) }; ((2 * 2) * (2 * 2))`,
+		},
+	}
+	for _, tt := range tests {
+		expected := testMacroProgram(tt.expected)
+		program := testMacroProgram(tt.input)
+		env := object.NewEnviornment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Errorf("expansion of %q failed: %s", tt.input, err)
+			continue
+		}
+		if expanded.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+		}
+	}
+}
+
+func TestMacroErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = macro(x) { x + 1 }; m(1)`, "1:22: type mismatch: QUOTE + INTEGER"},
+		{`let m = macro(x) { 1 }; m(1)`, "1:26: macro m has to return back a quote, got INTEGER"},
+		{`let m = macro(x) { quote(x) }; m(1, 2)`, "1:33: wrong number of arguments: want=1, got=2"},
+		{`let m = macro() { quote(unquote(fn() { 1 })) }; m()`, "1:24: unquote of FUNCTION is not supported"},
+		{`let m = macro() { quote(unquote(missing)) }; m()`, "1:33: identifier not found: missing"},
+	}
+	for _, tt := range tests {
+		program := testMacroProgram(tt.input)
+		env := object.NewEnviornment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("expected an expansion error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+	errObj, ok := testEval("let f = fn() { let m = macro(x) { x }; m(1) }; f()").(*object.Error)
+	if !ok || errObj.Message != "macros can only be bound with a top level let statement" {
+		t.Errorf("expected error for a macro literal inside of a function. got=%v", errObj)
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let r = 0; try { throw 5 } catch (e) { r = e }; r", 5},
+		{`let r = ""; try { 1 + "a" } catch (e) { r = e.kind + ": " + e.message }; r`, "TypeError: type mismatch: INTEGER + STRING"},
+		{`let r = ""; try { missing } catch (e) { r = e.kind }; r`, "ReferenceError"},
+		{`let r = ""; try { [1].size } catch (e) { r = e.kind }; r`, "TypeError"},
+		{`let r = ""; try { throw error("bad", "ValueError") } catch (e) { r = e.kind + e.message }; r`, "ValueErrorbad"},
+		{"let r = 0; try { 1() } catch { r = 1 } finally { r = r + 10 }; r", 11},
+		{"let r = 0; try { r = 1 } finally { r = r * 5 }; r", 5},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let r = []; let f = fn() { try { return 1 } finally { r.push(2) } }; f() + r[0]", 3},
+		{"let r = 0; let f = fn(x) { if (x == 0) { throw 7 } f(x - 1) }; try { f(3) } catch (e) { r = e }; r", 7},
+		{"let r = 0; try { try { throw 1 } finally { r = 10 } } catch (e) { r = r + e }; r", 11},
+		{"let r = 0; try { try { throw 1 } catch (e) { throw e + 1 } } catch (e) { r = e }; r", 2},
+		{"let r = 0; for (x of [1, 2, 3]) { try { if (x == 2) { continue } r += x } finally { r += 10 } }; r", 34},
+		{"let r = 0; while (true) { try { break } finally { r = 1 } }; r", 1},
+		{"let r = 0; for (x of [1, 2]) { try { throw x } catch (e) { r += e } }; r", 3},
+		{"let e = 1; try { throw 2 } catch (e) { e }; e", 1},
+		{`let r = ""; let f = fn(n) { f(n + 1) }; try { f(0) } catch (e) { r = e.kind + ": " + e.message }; r`, "RangeError: stack overflow"},
+		{"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(1000)", 1000},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		}
+	}
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "oops"`, "uncaught exception: oops"},
+		{`throw error("bad")`, "bad"},
+		{"try { 1 + true } catch (e) { throw e }", "type mismatch: INTEGER + BOOLEAN"},
+		{"try { throw 1 } finally { 2 }", "uncaught exception: 1"},
+		{"try { throw 1 } catch (e) { missing }", "identifier not found: missing"},
+		{"try { 1 } finally { throw 2 }", "uncaught exception: 2"},
+		{"let f = fn() { f() }; f()", "stack overflow"},
+	}
+	for _, tt := range errors {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestExceptionStack(t *testing.T) {
+	evaluated := testEval("let r = \"\"; try {\n  1 + true\n} catch (e) { r = e.stack }; r")
+	str, ok := evaluated.(*object.String)
+	if !ok || str.Value != "    at 2:5" {
+		t.Errorf("wrong stack. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + true", "    at 1:3"},
+		{"let f = fn() { 1 + true };\nf()", "    at f (1:18)\n    at 2:2"},
+		{"function outer() { [1].map(fn() { -true }) }\nouter()", "    at outer (1:23)\n    at 2:6"},
+		{"let g = fn(h) { h() };\ng(fn() { len(1) })", "    at <anonymous> (2:13)\n    at g (1:18)\n    at 2:2"},
+	}
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error for %q", tt.input)
+			continue
+		}
+		if stack := diagnostic.Trace(errObj.Trace()); stack != tt.expected {
+			t.Errorf("wrong stack for %q.\nwant=%q\ngot =%q", tt.input, tt.expected, stack)
+		}
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	defer func(policy object.OverflowPolicy) { object.Overflow = policy }(object.Overflow)
+	input := "let max = 9223372036854775807; [max + 1, -(-max - 1), max * 3 / 3 > max]"
+	expected := map[object.OverflowPolicy]string{
+		object.OverflowWrap:    "[-9223372036854775808, -9223372036854775808, false]",
+		object.OverflowError:   "integer overflow: 9223372036854775807 + 1",
+		object.OverflowPromote: "[9223372036854775808n, 9223372036854775808n, false]",
+	}
+	for policy, want := range expected {
+		object.Overflow = policy
+		evaluated := testEval(input)
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		}
+		if got != want {
+			t.Errorf("wrong result with %s policy. want=%s, got=%s", policy, want, got)
+		}
+	}
+}
+
+func TestBigIntArithmetic(t *testing.T) {
+	defer func(policy object.OverflowPolicy) { object.Overflow = policy }(object.Overflow)
+	object.Overflow = object.OverflowPromote
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let big = 9223372036854775807 + 1; big - 1", "9223372036854775807n"},
+		{"let big = 9223372036854775807 + 1; big * big", "85070591730234615865843651857942052864n"},
+		{"let big = 9223372036854775807 + 1; -big / 2", "-4611686018427387904n"},
+		{"let big = 9223372036854775807 + 1; [big == big + 0, big != 1, big < 1, big >= big]", "[true, true, false, true]"},
+		{"let big = 9223372036854775807 + 1; big / 0", "division by zero"},
+		{"let big = 9223372036854775807 + 1; big + 1.5", "type mismatch: BIGINT + FLOAT"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestBigIntLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"18446744073709551616n * 18446744073709551616n", "340282366920938463463374607431768211456n"},
+		{"-10n / 3n + 1", "-2n"},
+		{"[2n > 1, 1n == 1, 3n <= 2n, 5n != 5n]", "[true, true, false, false]"},
+		{`let h = {10n: "big", 10: "small"}; [h[10n], h[10]]`, "[big, small]"},
+		{`[bigint(7), bigint("-12345678901234567890"), bigint(3.0), int(42n), int(2.9), int("17")]`, "[7n, -12345678901234567890n, 3n, 42, 2, 17]"},
+		{"int(18446744073709551616n)", "cannot convert 18446744073709551616n to an integer"},
+		{"bigint(0.5)", "cannot convert 0.5 to a big integer"},
+		{"bigint(true)", "argument to bigint is invalid. got=BOOLEAN"},
+		{"1n + 0.5", "type mismatch: BIGINT + FLOAT"},
+		{"1n / 0n", "division by zero"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	defer func(policy object.OverflowPolicy) { object.Overflow = policy }(object.Overflow)
+	object.Overflow = object.OverflowWrap
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[7 % 3, -7 % 3, 7.5 % 2, 2 ** 10, 2 ** 3 ** 2, -2 ** 2, 2 ** -1, 4 ** 0.5]", "[1, -1, 1.5, 1024, 512, -4, 0.5, 2]"},
+		{"[6 & 3, 6 | 3, 6 ^ 3, ~5, 1 << 4, -16 >> 2, -1 >>> 60, 1 << 64]", "[2, 7, 5, -6, 16, -4, 15, 1]"},
+		{"[1 + 2 << 1, 1 | 2 & 3, 2 * 3 ** 2]", "[6, 3, 18]"},
+		{"[2n ** 100n, 7n % -3n, ~5n, 1n << 70n, -5n >> 1n, 6n | 1, 1n << -1n]", "[1267650600228229401496703205376n, 1n, -6n, 1180591620717411303424n, -3n, 7n, 0n]"},
+		{"3 ** 40", "-6289078614652622815"},
+		{"7 % 0", "division by zero"},
+		{"7n % 0n", "division by zero"},
+		{"2n ** -1n", "negative exponent: 2n ** -1n"},
+		{"2n ** 100000000n", "big integer too large: 2n ** 100000000n"},
+		{"1n >>> 1n", "unknown operator: BIGINT >>> BIGINT"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"~1.5", "unknown operator: ~FLOAT"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if errObj, ok := evaluated.(*object.Error); ok {
+			got = errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package evaluator
+
+import (
+	"compiler/ast"
+	"compiler/diagnostic"
+	"compiler/object"
+)
+
+// Throw stops the program like a runtime error does, The error carries the thrown value for the catch block
+func evalThrowStatement(node *ast.ThrowStatement, env *object.Enviornment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	return &object.Error{Message: object.ThrownMessage(value), Pos: node.Pos(), Thrown: value}
+}
+
+// Try runs the catch block when the block failed and the finally block in any case, The finally block decides
+// the result when it returns, breaks, continues or fails itself. Otherwise the result of the block or of the
+// catch block is passed on when it left the statement early. Every block gets its own enviornment and the
+// parameter of the catch block is bound in the enviornment of the catch block.
+func evalTryStatement(node *ast.TryStatement, env *object.Enviornment) object.Object {
+	result := Eval(node.Block, object.NewEnclosedEnviornment(env))
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		catchEnv := object.NewEnclosedEnviornment(env)
+		if node.Param != nil {
+			catchEnv.Set(node.Param.Value, exceptionOf(err))
+		}
+		result = Eval(node.Catch, catchEnv)
+	}
+	if node.Finally != nil {
+		finally := Eval(node.Finally, object.NewEnclosedEnviornment(env))
+		if leavesStatement(finally) {
+			return finally
+		}
+	}
+	if leavesStatement(result) {
+		return result
+	}
+	return nil
+}
+
+// Returns back the value the catch block gets for the error, Runtime errors are turned into exceptions. The
+// stack of the error is kept by exceptions which do not have a stack yet.
+func exceptionOf(err *object.Error) object.Object {
+	if err.Thrown == nil {
+		exception := object.NewException(err.Kind, err.Message)
+		exception.Stack = caughtTrace(err)
+		return exception
+	}
+	if exception, ok := err.Thrown.(*object.Exception); ok && len(exception.Stack) == 0 {
+		exception.Stack = caughtTrace(err)
+	}
+	return err.Thrown
+}
+
+// Returns back the frames of the error up to the catch block, The last frame is the one of the function the
+// catch block belongs to and has its name. It has no name in the main program.
+func caughtTrace(err *object.Error) []diagnostic.Frame {
+	stack := append([]diagnostic.Frame{}, err.Trace()...)
+	if len(calls) > 0 {
+		stack[len(stack)-1].Function = calls[len(calls)-1]
+	}
+	return stack
+}
+
+// Results which leave the enclosing statements, Like they do in evalBlockStatement
+func leavesStatement(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.ReturnValue, *object.Error, *object.Break, *object.Continue:
+		return true
+	}
+	return false
+}
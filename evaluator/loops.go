@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"compiler/ast"
+	"compiler/object"
+)
+
+// Loops are statements, They return back nil unless the body returned from the function or failed. Every
+// run of the body gets a new enviornment for the names declared in it.
+
+func evalWhileStatement(node *ast.WhileStatement, env *object.Enviornment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+		if result, stop := evalLoopBody(node.Body, env); stop {
+			return result
+		}
+	}
+}
+
+// Names declared in the init statement belong to the loop, Like in JavaScript every run of the body gets its
+// own copy of them so closures of the body keep the values of their run. The update changes the copy of the
+// next run.
+func evalForStatement(node *ast.ForStatement, env *object.Enviornment) object.Object {
+	env = object.NewEnclosedEnviornment(env)
+	if node.Init != nil {
+		if init := Eval(node.Init, env); isError(init) {
+			return init
+		}
+	}
+	env = env.CopyBindings()
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, env)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+		if result, stop := evalLoopBody(node.Body, env); stop {
+			return result
+		}
+		env = env.CopyBindings()
+		if node.Update != nil {
+			if update := Eval(node.Update, env); isError(update) {
+				return update
+			}
+		}
+	}
+}
+
+func evalForEachStatement(node *ast.ForEachStatement, env *object.Enviornment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	iterator, err := object.NewIterator(iterable, node.Operator == "in")
+	if err != nil {
+		return newErrorFrom(err)
+	}
+	for {
+		value, ok := iterator.Next()
+		if !ok {
+			return nil
+		}
+		iterationEnv := object.NewEnclosedEnviornment(env)
+		if node.Constant {
+			iterationEnv.SetConst(node.Variable.Value, value)
+		} else {
+			iterationEnv.Set(node.Variable.Value, value)
+		}
+		if result, stop := evalLoopBody(node.Body, iterationEnv); stop {
+			return result
+		}
+	}
+}
+
+// Runs the body of a loop once, Stop is true when the loop has to end. The result is then what the loop
+// statement returns back, nil for break and the value itself for return values and errors.
+func evalLoopBody(body *ast.BlockStatement, env *object.Enviornment) (object.Object, bool) {
+	switch result := Eval(body, object.NewEnclosedEnviornment(env)).(type) {
+	case *object.Break:
+		return nil, true
+	case *object.ReturnValue, *object.Error:
+		return result, true
+	}
+	return nil, false
+}
@@ -0,0 +1,159 @@
+package evaluator
+
+import (
+	"compiler/ast"
+	"compiler/diagnostic"
+	"compiler/object"
+	"compiler/token"
+	"strconv"
+)
+
+// Returns back the argument without evaluating it, Only the arguments of unquote calls inside of it are
+// evaluated and their values are put back into the quoted code
+func quote(node *ast.CallExpression, env *object.Enviornment) object.Object {
+	if len(node.Arguments) != 1 {
+		return newTypeError("wrong number of arguments. got=%d, want=1", len(node.Arguments))
+	}
+	var err *object.Error
+	quoted := ast.Modify(node.Arguments[0], func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || !isCallTo(call, "unquote") || err != nil {
+			return node
+		}
+		if len(call.Arguments) != 1 {
+			err = newTypeError("wrong number of arguments. got=%d, want=1", len(call.Arguments))
+			return node
+		}
+		value := Eval(call.Arguments[0], env)
+		if isError(value) {
+			err = value.(*object.Error)
+			return node
+		}
+		converted, ok := objectToNode(value, call.Token)
+		if !ok {
+			err = newError("unquote of %s is not supported", value.Type())
+			return node
+		}
+		return converted
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: quoted}
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// Turns the value of an unquote call back into code, The nodes get the position of the unquote call
+func objectToNode(obj object.Object, at token.Token) (ast.Node, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		tok := token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10), Pos: at.Pos}
+		return &ast.IntegerLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Float:
+		tok := token.Token{Type: token.FLOAT, Literal: obj.Inspect(), Pos: at.Pos}
+		return &ast.FloatLiteral{Token: tok, Value: obj.Value}, true
+	case *object.BigInt:
+		tok := token.Token{Type: token.BIGINT, Literal: obj.Inspect(), Pos: at.Pos}
+		return &ast.BigIntLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Boolean:
+		tok := token.Token{Type: token.FALSE, Literal: "false", Pos: at.Pos}
+		if obj.Value {
+			tok = token.Token{Type: token.TRUE, Literal: "true", Pos: at.Pos}
+		}
+		return &ast.Boolean{Token: tok, Value: obj.Value}, true
+	case *object.String:
+		tok := token.Token{Type: token.STRING, Literal: obj.Value, Pos: at.Pos}
+		return &ast.StringLiteral{Token: tok, Value: obj.Value}, true
+	case *object.Quote:
+		return obj.Node, true
+	}
+	return nil, false
+}
+
+// Binds the macros of the top level let statements in the enviornment and removes the statements from the
+// program, Macros are kept in their own enviornment which is only used for expansion
+func DefineMacros(program *ast.Program, env *object.Enviornment) {
+	statements := []ast.Statement{}
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+		macro, ok := let.Value.(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+		env.Set(let.Name.Value, &object.Macro{Parameters: macro.Parameters, Body: macro.Body, Env: env})
+	}
+	program.Statements = statements
+}
+
+// Replaces the calls of macros with the code the macros return back, The arguments are passed to the macro as
+// quotes of the unevaluated code. Errors are returned back as diagnostics at the macro call.
+func ExpandMacros(program *ast.Program, env *object.Enviornment) (*ast.Program, error) {
+	var expansionError error
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || expansionError != nil {
+			return node
+		}
+		macro, ok := macroOf(call, env)
+		if !ok {
+			return node
+		}
+		if len(call.Arguments) != len(macro.Parameters) {
+			expansionError = diagnostic.New(call.Pos(), "wrong number of arguments: want=%d, got=%d", len(macro.Parameters), len(call.Arguments))
+			return node
+		}
+		macroEnv := object.NewEnclosedEnviornment(macro.Env)
+		for i, param := range macro.Parameters {
+			macroEnv.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+		}
+		evaluated := unwrapReturnValue(Eval(macro.Body, macroEnv))
+		if errObj, ok := evaluated.(*object.Error); ok {
+			pos := errObj.Pos
+			if !pos.IsValid() {
+				pos = call.Pos()
+			}
+			expansionError = &diagnostic.Diagnostic{Pos: pos, Message: errObj.Message}
+			return node
+		}
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			expansionError = diagnostic.New(call.Pos(), "macro %s has to return back a quote, got %s", call.Function.String(), typeOf(evaluated))
+			return node
+		}
+		return quote.Node
+	})
+	if expansionError != nil {
+		return program, expansionError
+	}
+	return expanded.(*ast.Program), nil
+}
+
+func macroOf(call *ast.CallExpression, env *object.Enviornment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+// Macro bodies without a value evaluate to nil
+func typeOf(obj object.Object) string {
+	if obj == nil {
+		return "NULL"
+	}
+	return string(obj.Type())
+}
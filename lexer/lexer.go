@@ -16,18 +16,30 @@ type Lexer interface {
 
 type lexer struct {
 	input        string
+	file         string
 	position     int
 	readPosition int
 	ch           byte
+	line         int // Line of the current character
+	column       int // Column of the current character
 }
 
 func New(input string) Lexer {
-	l := &lexer{input: input}
+	return NewWithFile("", input)
+}
+
+// Creates a lexer which records the given file name in the position of every token
+func NewWithFile(file, input string) Lexer {
+	l := &lexer{input: input, file: file, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -35,20 +47,33 @@ func (l *lexer) readChar() {
 	}
 	l.position = l.readPosition
 	l.readPosition++
+	l.column++
+}
+
+func (l *lexer) currentPosition() token.Position {
+	return token.Position{File: l.file, Line: l.line, Column: l.column, Offset: l.position}
 }
 
+// Returns back the next token along with the position where the token starts
 func (l *lexer) NextToken() token.Token {
 	l.skipWhitespace()
-
-	if l.ch == '#' {
+	for l.ch == '#' {
 		l.skipComment()
 	}
+	pos := l.currentPosition()
+	tok := l.readToken()
+	tok.Pos = pos
+	return tok
+}
 
+func (l *lexer) readToken() token.Token {
 	var tok token.Token
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
 			tok = l.readTwoCharToken(token.EQ)
+		} else if l.peekChar() == '>' {
+			tok = l.readTwoCharToken(token.ARROW)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -62,6 +87,8 @@ func (l *lexer) NextToken() token.Token {
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -69,32 +96,73 @@ func (l *lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.PLUS_ASSIGN)
+		} else if l.peekChar() == '+' {
+			tok = l.readTwoCharToken(token.INCREMENT)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.MINUS_ASSIGN)
+		} else if l.peekChar() == '-' {
+			tok = l.readTwoCharToken(token.DECREMENT)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTARISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.ASTARISK_ASSIGN)
+		} else if l.peekChar() == '*' {
+			tok = l.readTwoCharToken(token.POWER)
+		} else {
+			tok = newToken(token.ASTARISK, l.ch)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case '<':
 		if l.peekChar() == '=' {
 			tok = l.readTwoCharToken(token.LE)
+		} else if l.peekChar() == '<' {
+			tok = l.readTwoCharToken(token.LSHIFT)
 		} else {
 			tok = newToken(token.LT, l.ch)
 		}
 	case '>':
 		if l.peekChar() == '=' {
 			tok = l.readTwoCharToken(token.GE)
+		} else if l.peekChar() == '>' {
+			tok = l.readTwoCharToken(token.RSHIFT)
+			if l.peekChar() == '>' {
+				l.readChar()
+				tok.Type = token.URSHIFT
+				tok.Literal = token.URSHIFT
+			}
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
 	case '&':
 		if l.peekChar() == '&' {
 			tok = l.readTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.AMPERSAND, l.ch)
 		}
 	case '|':
 		if l.peekChar() == '|' {
 			tok = l.readTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.PIPE, l.ch)
 		}
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
@@ -135,7 +203,7 @@ func (l *lexer) skipWhitespace() {
 }
 
 func (l *lexer) skipComment() {
-	for l.ch != '\n' && l.ch != '\r' {
+	for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
 		l.readChar()
 	}
 	l.skipWhitespace()
@@ -186,6 +254,17 @@ func (l *lexer) readNumber() string {
 
 func (l *lexer) readNumberToken() token.Token {
 	intPart := l.readNumber()
+	// Big integers are written with the n suffix of JavaScript, The suffix is kept in the literal
+	if l.ch == 'n' {
+		l.readChar()
+		if isLetter(l.ch) || isDigit(l.ch) {
+			return l.readIllegalNumber(intPart + "n")
+		}
+		return token.Token{
+			Type:    token.BIGINT,
+			Literal: intPart + "n",
+		}
+	}
 	if l.ch != '.' {
 		return token.Token{
 			Type:    token.INT,
@@ -195,12 +274,26 @@ func (l *lexer) readNumberToken() token.Token {
 
 	l.readChar()
 	fracPart := l.readNumber()
+	// Only integers can be big integers, So the n suffix after a fraction is rejected
+	if l.ch == 'n' {
+		return l.readIllegalNumber(intPart + "." + fracPart)
+	}
 	return token.Token{
 		Type:    token.FLOAT,
 		Literal: intPart + "." + fracPart,
 	}
 }
 
+// Reads the identifier characters which follow a number that can not be followed by them, The whole run
+// becomes one ILLEGAL token so that 123nabc is not read as 123n and abc
+func (l *lexer) readIllegalNumber(number string) token.Token {
+	rest := l.read(func(ch byte) bool { return isLetter(ch) || isDigit(ch) })
+	return token.Token{
+		Type:    token.ILLEGAL,
+		Literal: number + rest,
+	}
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -50,7 +50,10 @@ func TestNexttoken(t *testing.T) {
 	macro(x, y) { x + y; };
 	"golang"
 	"go lang"
+	x += 1 -= *= /= x++ --x;
 	[1, 2];
+	obj.push(x);
+	function f(a) => a;
 	`
 
 	tests := []struct {
@@ -205,12 +208,38 @@ func TestNexttoken(t *testing.T) {
 		{token.SEMICOLON, ";"},
 		{token.STRING, "golang"},
 		{token.STRING, "go lang"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTARISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.IDENT, "x"},
+		{token.INCREMENT, "++"},
+		{token.DECREMENT, "--"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
 		{token.LBRACKET, "["},
 		{token.INT, "1"},
 		{token.COMMA, ","},
 		{token.INT, "2"},
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "obj"},
+		{token.DOT, "."},
+		{token.IDENT, "push"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "function"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 
@@ -230,3 +259,129 @@ func TestNexttoken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n# comment\n  x >= \"ab\"; # trailing"
+
+	tests := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+		expectedOffset int
+	}{
+		{token.LET, 1, 1, 0},
+		{token.IDENT, 1, 5, 4},
+		{token.ASSIGN, 1, 7, 6},
+		{token.INT, 1, 9, 8},
+		{token.SEMICOLON, 1, 11, 10},
+		{token.IDENT, 3, 3, 24},
+		{token.GE, 3, 5, 26},
+		{token.STRING, 3, 8, 29},
+		{token.SEMICOLON, 3, 12, 33},
+		{token.EOF, 3, 24, 45},
+	}
+
+	l := NewWithFile("test.bjs", input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos.File != "test.bjs" {
+			t.Errorf("tests[%d] - file wrong. expected=%q, got=%q", i, "test.bjs", tok.Pos.File)
+		}
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn || tok.Pos.Offset != tt.expectedOffset {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d (offset %d), got=%d:%d (offset %d)",
+				i, tt.expectedLine, tt.expectedColumn, tt.expectedOffset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestBigIntTokens(t *testing.T) {
+	input := "123n + 4;5n"
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.BIGINT, "123n"},
+		{token.PLUS, "+"},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.BIGINT, "5n"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestIllegalBigIntTokens(t *testing.T) {
+	input := "123nabc 1.5n 7n1;8n"
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.ILLEGAL, "123nabc"},
+		{token.ILLEGAL, "1.5n"},
+		{token.ILLEGAL, "7n1"},
+		{token.SEMICOLON, ";"},
+		{token.BIGINT, "8n"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseTokens(t *testing.T) {
+	input := "a % b ** c & d | e ^ ~f << g >> h >>> i && j || k *= l <= m >= n"
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "b"},
+		{token.POWER, "**"},
+		{token.IDENT, "c"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "d"},
+		{token.PIPE, "|"},
+		{token.IDENT, "e"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "f"},
+		{token.LSHIFT, "<<"},
+		{token.IDENT, "g"},
+		{token.RSHIFT, ">>"},
+		{token.IDENT, "h"},
+		{token.URSHIFT, ">>>"},
+		{token.IDENT, "i"},
+		{token.AND, "&&"},
+		{token.IDENT, "j"},
+		{token.OR, "||"},
+		{token.IDENT, "k"},
+		{token.ASTARISK_ASSIGN, "*="},
+		{token.IDENT, "l"},
+		{token.LE, "<="},
+		{token.IDENT, "m"},
+		{token.GE, ">="},
+		{token.IDENT, "n"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
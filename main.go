@@ -1,11 +1,8 @@
 package main
 
 import (
-	"compiler/constants"
-	"compiler/relp"
-	"fmt"
+	"compiler/cli"
 	"os"
-	"strings"
 )
 
 /*
@@ -16,34 +13,5 @@ import (
 */
 
 func main() {
-	// readFile()
-	tokenDebug := os.Getenv("BJS_DEBUG")
-	tokenCompilationMode := os.Getenv("BJS_COMPILE_MODE")
-	if tokenDebug == "true" {
-		fmt.Printf(constants.LOGO)
-		fmt.Println("JavaScript For Servers, Blazingly Fast and Compiled")
-		fmt.Println("Welcome to BJS lang debugger, This is your new RELP for debugging purpose")
-		fmt.Println("Please type in expressions to Debug: ")
-		if tokenCompilationMode == "true" {
-			fmt.Println("Running in Compilation mode")
-			relp.StartRELP(os.Stdin, os.Stdout, true)
-		} else {
-			fmt.Println("Running in Interpreter mode: default mode")
-			relp.StartRELP(os.Stdin, os.Stdout, false)
-		}
-	} else {
-		readFile()
-	}
-}
-
-func readFile() {
-	osArgs := os.Args
-	if len(osArgs) < 2 {
-		panic("not enough arguments to read file")
-	}
-	filename := osArgs[0]
-	fileExtension := strings.Split(filename, ".")[1]
-	if fileExtension != "bjs" {
-		panic("file extension is wrong, make sure you are using bjs file extension.")
-	}
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
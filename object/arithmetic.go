@@ -0,0 +1,120 @@
+package object
+
+import (
+	"compiler/constants"
+	"math"
+	"math/big"
+)
+
+// Policy for the results of integer arithmetic which do not fit into 64 bits
+type OverflowPolicy string
+
+const (
+	OverflowWrap    OverflowPolicy = "wrap"    // Results wrap around like the integers of Go do
+	OverflowError   OverflowPolicy = "error"   // Results which overflow are runtime errors
+	OverflowPromote OverflowPolicy = "promote" // Results which overflow become big integers
+)
+
+// Overflow policy of both engines, It is set by the --overflow flag of the CLI
+var Overflow = OverflowWrap
+
+// Returns back the overflow policy with the given name
+func ParseOverflowPolicy(name string) (OverflowPolicy, bool) {
+	switch policy := OverflowPolicy(name); policy {
+	case OverflowWrap, OverflowError, OverflowPromote:
+		return policy, true
+	}
+	return "", false
+}
+
+// Does the integer arithmetic of both engines, Division by zero is an error and results which overflow are
+// handled by the overflow policy. Negative exponents give back a float like 2 ** -1 is 0.5. The bitwise
+// operators work on all 64 bits and never overflow, Shift counts are taken modulo 64 the way JavaScript
+// takes them modulo 32.
+func IntegerArithmetic(operator string, left, right int64) (Object, error) {
+	var result int64
+	var overflow bool
+	switch operator {
+	case "+":
+		result = left + right
+		overflow = (right > 0 && result < left) || (right < 0 && result > left)
+	case "-":
+		result = left - right
+		overflow = (right > 0 && result > left) || (right < 0 && result < left)
+	case "*":
+		result, overflow = multiply(left, right)
+	case "/":
+		if right == 0 {
+			return nil, RangeErrorf("division by zero")
+		}
+		result = left / right
+		overflow = left == math.MinInt64 && right == -1
+	case "%":
+		if right == 0 {
+			return nil, RangeErrorf("division by zero")
+		}
+		result = left % right
+	case "**":
+		if right < 0 {
+			return &Float{Value: math.Pow(float64(left), float64(right))}, nil
+		}
+		result, overflow = power(left, right)
+	case "&":
+		result = left & right
+	case "|":
+		result = left | right
+	case "^":
+		result = left ^ right
+	case "<<":
+		result = left << (uint64(right) & 63)
+	case ">>":
+		result = left >> (uint64(right) & 63)
+	case ">>>":
+		result = int64(uint64(left) >> (uint64(right) & 63))
+	default:
+		return nil, TypeErrorf("unknown operator: %s %s %s", constants.INTEGER_OBJECT, operator, constants.INTEGER_OBJECT)
+	}
+	if !overflow || Overflow == OverflowWrap {
+		return &Integer{Value: result}, nil
+	}
+	if Overflow == OverflowError {
+		return nil, RangeErrorf("integer overflow: %d %s %d", left, operator, right)
+	}
+	return BigArithmetic(operator, big.NewInt(left), big.NewInt(right))
+}
+
+// Multiplies the integers and reports whether the product overflowed
+func multiply(left, right int64) (int64, bool) {
+	result := left * right
+	return result, left != 0 && (result/left != right || (left == -1 && right == math.MinInt64))
+}
+
+// Raises the base to the exponent by squaring, Reports whether one of the products overflowed. The wrapped
+// result is still the exact result modulo 64 bits.
+func power(base, exponent int64) (int64, bool) {
+	result, overflow := int64(1), false
+	for exponent > 0 {
+		var o bool
+		if exponent&1 == 1 {
+			result, o = multiply(result, base)
+			overflow = overflow || o
+		}
+		exponent >>= 1
+		if exponent > 0 {
+			base, o = multiply(base, base)
+			overflow = overflow || o
+		}
+	}
+	return result, overflow
+}
+
+// Negates the integer, Only the smallest integer overflows and it is handled by the overflow policy
+func NegateInteger(value int64) (Object, error) {
+	if value != math.MinInt64 || Overflow == OverflowWrap {
+		return &Integer{Value: -value}, nil
+	}
+	if Overflow == OverflowError {
+		return nil, RangeErrorf("integer overflow: -(%d)", value)
+	}
+	return &BigInt{Value: new(big.Int).Neg(big.NewInt(value))}, nil
+}
@@ -0,0 +1,118 @@
+package object
+
+import (
+	"compiler/constants"
+	"hash/fnv"
+	"math/big"
+)
+
+// Big integer of arbitrary precision, It is written with the n suffix like the BigInt of JavaScript and
+// integers which overflow become big integers when the overflow policy asks for it. Integers in arithmetic
+// with big integers are promoted instead of being an error like in JavaScript, Since overflowing results mix
+// with the integers of the program.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType { return constants.BIGINT_OBJECT }
+func (b *BigInt) Inspect() string  { return b.Value.String() + "n" }
+
+// Big integers are hashed by their digits, So equal big integers are the same key. They are not the same key
+// as the integer of the same value, Like a float is not.
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// Checks if the object is an integer or a big integer
+func IsInteger(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInt:
+		return true
+	}
+	return false
+}
+
+// Returns back the value of an integer or a big integer as big.Int, Used when integers are mixed with big
+// integers. The returned value must not be changed.
+func ToBigInt(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInt:
+		return obj.Value
+	}
+	return nil
+}
+
+// Largest number of bits a big integer may grow to by exponentiation or shifts, So that 2n ** 10000000000n
+// is an error instead of running out of memory
+const maxBigIntBits = 1 << 24
+
+// Does the arithmetic of big integers for both engines, Division truncates towards zero like it does for
+// integers. Shifts by a negative count shift into the other direction like the BigInt of JavaScript, There
+// is no unsigned shift since big integers have no fixed width.
+func BigArithmetic(operator string, left, right *big.Int) (Object, error) {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(left, right)
+	case "-":
+		result.Sub(left, right)
+	case "*":
+		result.Mul(left, right)
+	case "/":
+		if right.Sign() == 0 {
+			return nil, RangeErrorf("division by zero")
+		}
+		result.Quo(left, right)
+	case "%":
+		if right.Sign() == 0 {
+			return nil, RangeErrorf("division by zero")
+		}
+		result.Rem(left, right)
+	case "**":
+		if right.Sign() < 0 {
+			return nil, RangeErrorf("negative exponent: %sn ** %sn", left, right)
+		}
+		if left.CmpAbs(big.NewInt(1)) > 0 && (!right.IsInt64() || right.Int64() > maxBigIntBits/int64(left.BitLen())) {
+			return nil, RangeErrorf("big integer too large: %sn ** %sn", left, right)
+		}
+		result.Exp(left, right, nil)
+	case "&":
+		result.And(left, right)
+	case "|":
+		result.Or(left, right)
+	case "^":
+		result.Xor(left, right)
+	case "<<", ">>":
+		count := new(big.Int).Set(right)
+		if operator == ">>" {
+			count.Neg(count)
+		}
+		return shiftBigInt(left, count)
+	default:
+		return nil, TypeErrorf("unknown operator: %s %s %s", constants.BIGINT_OBJECT, operator, constants.BIGINT_OBJECT)
+	}
+	return &BigInt{Value: result}, nil
+}
+
+// Shifts the big integer to the left by the count, Negative counts shift to the right and round towards
+// negative infinity
+func shiftBigInt(value, count *big.Int) (Object, error) {
+	if count.Sign() >= 0 {
+		if value.Sign() != 0 && (!count.IsInt64() || count.Int64() > maxBigIntBits-int64(value.BitLen())) {
+			return nil, RangeErrorf("big integer too large: %sn << %sn", value, count)
+		}
+		return &BigInt{Value: new(big.Int).Lsh(value, uint(count.Uint64()))}, nil
+	}
+	count = new(big.Int).Neg(count)
+	if !count.IsInt64() || count.Int64() > int64(value.BitLen()) {
+		if value.Sign() < 0 {
+			return &BigInt{Value: big.NewInt(-1)}, nil
+		}
+		return &BigInt{Value: new(big.Int)}, nil
+	}
+	return &BigInt{Value: new(big.Int).Rsh(value, uint(count.Uint64()))}, nil
+}
@@ -0,0 +1,227 @@
+package object
+
+import (
+	"compiler/constants"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Stdout is where the prints builtin writes to, It can be replaced to capture the output of a program.
+var Stdout io.Writer = os.Stdout
+
+// Builtin functions shared by the evaluator and the virtual machine, Make and add more builtin functions here.
+// The registry is a slice so that the compiler can refer to a builtin by its index, New builtins have to be
+// appended at the end to keep the indexes of compiled programs stable.
+// Builtins return back nil when there is no value, The engines turn it into their null value.
+// TODO: There is repetative checks for single array fn, Make sure you put them in single array checker code
+// This will make things simpler
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(len(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			default:
+				return newTypeError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		}},
+	},
+	// First function returns back the first element in an array
+	{
+		"first",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != constants.ARRAY_OBJECT {
+				return newTypeError("argument to first is invalid. got=%s", args[0].Type())
+			}
+			arr := args[0].(*Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+			return nil
+		}},
+	},
+	// Gets the last element in an array and then push it to commandline for interpreter
+	{
+		"last",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != constants.ARRAY_OBJECT {
+				return newTypeError("argument to last is invalid. got=%s", args[0].Type())
+			}
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				return arr.Elements[length-1]
+			}
+			return nil
+		}},
+	},
+	// Rest function for array returns you back the array popping the first element from array.
+	{
+		"rest",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != constants.ARRAY_OBJECT {
+				return newTypeError("argument to rest is invalid. got=%s", args[0].Type())
+			}
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]Object, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &Array{Elements: newElements}
+			}
+			return nil
+		}},
+	},
+	// Push function pushes things to array
+	{
+		"push",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newTypeError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if args[0].Type() != constants.ARRAY_OBJECT {
+				return newTypeError("argument to push is invalid. got=%s", args[0].Type())
+			}
+			arr := args[0].(*Array)
+			length := len(arr.Elements)
+			newElements := make([]Object, length+1)
+			copy(newElements, arr.Elements)
+			newElements[length] = args[1]
+			return &Array{Elements: newElements}
+		}},
+	},
+	// Prints string to console
+	{
+		"prints",
+		&Builtin{Fn: func(args ...Object) Object {
+			for _, arg := range args {
+				fmt.Fprintln(Stdout, arg.Inspect())
+			}
+			return nil
+		}},
+	},
+	// Creates an exception for throw, The kind defaults to Error
+	{
+		"error",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newTypeError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			message, ok := args[0].(*String)
+			if !ok {
+				return newTypeError("argument to error is invalid. got=%s", args[0].Type())
+			}
+			kind := "Error"
+			if len(args) == 2 {
+				k, ok := args[1].(*String)
+				if !ok {
+					return newTypeError("argument to error is invalid. got=%s", args[1].Type())
+				}
+				kind = k.Value
+			}
+			return &Exception{Kind: kind, Message: message.Value}
+		}},
+	},
+	// Converts an integer, a float without a fraction or a string of digits into a big integer
+	{
+		"bigint",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *BigInt:
+				return arg
+			case *Integer:
+				return &BigInt{Value: big.NewInt(arg.Value)}
+			case *Float:
+				if math.IsInf(arg.Value, 0) || math.IsNaN(arg.Value) || arg.Value != math.Trunc(arg.Value) {
+					return newRangeError("cannot convert %s to a big integer", arg.Inspect())
+				}
+				value, _ := big.NewFloat(arg.Value).Int(nil)
+				return &BigInt{Value: value}
+			case *String:
+				value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+				if !ok {
+					return newRangeError("cannot convert %q to a big integer", arg.Value)
+				}
+				return &BigInt{Value: value}
+			default:
+				return newTypeError("argument to bigint is invalid. got=%s", args[0].Type())
+			}
+		}},
+	},
+	// Converts a big integer, a float or a string of digits into an integer, Floats are truncated towards zero
+	{
+		"int",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Integer:
+				return arg
+			case *BigInt:
+				if !arg.Value.IsInt64() {
+					return newRangeError("cannot convert %s to an integer", arg.Inspect())
+				}
+				return &Integer{Value: arg.Value.Int64()}
+			case *Float:
+				// Floats from 2^63 on do not fit, The float of math.MaxInt64 is already 2^63
+				if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+					return newRangeError("cannot convert %s to an integer", arg.Inspect())
+				}
+				return &Integer{Value: int64(arg.Value)}
+			case *String:
+				value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 0, 64)
+				if err != nil {
+					return newRangeError("cannot convert %q to an integer", arg.Value)
+				}
+				return &Integer{Value: value}
+			default:
+				return newTypeError("argument to int is invalid. got=%s", args[0].Type())
+			}
+		}},
+	},
+}
+
+// Returns back the builtin with the given name or nil if there is no such builtin
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func newTypeError(format string, a ...interface{}) *Error {
+	return &Error{Kind: TypeErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+func newRangeError(format string, a ...interface{}) *Error {
+	return &Error{Kind: RangeErrorKind, Message: fmt.Sprintf(format, a...)}
+}
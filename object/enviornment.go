@@ -2,21 +2,42 @@
 // This is used to make sure the object associated with string is found back.
 package object
 
+import "sort"
+
 type Enviornment struct {
-	store map[string]Object
-	outer *Enviornment
+	store     map[string]Object
+	constants map[string]bool
+	outer     *Enviornment
 }
 
 func NewEnviornment() *Enviornment {
 	s := make(map[string]Object)
-	return &Enviornment{store: s, outer: nil}
+	return &Enviornment{store: s, constants: map[string]bool{}, outer: nil}
 }
 
 func (e *Enviornment) Set(name string, value Object) Object {
 	e.store[name] = value
+	delete(e.constants, name)
+	return value
+}
+
+// Binds the name to a value which can not be assigned to afterwards
+func (e *Enviornment) SetConst(name string, value Object) Object {
+	e.store[name] = value
+	e.constants[name] = true
 	return value
 }
 
+// Reports whether the binding that Get finds for the name is a constant
+func (e *Enviornment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.constants[name]
+		}
+	}
+	return false
+}
+
 // Get the outer and inner scope for checking in the functiond with local variables
 // This supports the closure for the interpreter
 func (e *Enviornment) Get(name string) (Object, bool) {
@@ -27,6 +48,42 @@ func (e *Enviornment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Assign updates the binding in the enviornment where the name was declared, It returns back false
+// when the name is not declared in this enviornment or any of its outer enviornments
+func (e *Enviornment) Assign(name string, value Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = value
+			return true
+		}
+	}
+	return false
+}
+
+// Returns back the names bound in this enviornment without the names of the outer enviornments, Sorted so
+// that listings are stable
+func (e *Enviornment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns back a new enviornment with the same outer enviornment and a copy of the bindings of this one, Used by
+// for loops to give every run of the body its own bindings of the loop variables
+func (e *Enviornment) CopyBindings() *Enviornment {
+	env := NewEnclosedEnviornment(e.outer)
+	for name, value := range e.store {
+		env.store[name] = value
+	}
+	for name := range e.constants {
+		env.constants[name] = true
+	}
+	return env
+}
+
 func NewEnclosedEnviornment(outer *Enviornment) *Enviornment {
 	env := NewEnviornment()
 	env.outer = outer
@@ -0,0 +1,118 @@
+package object
+
+import (
+	"compiler/constants"
+	"compiler/diagnostic"
+	"compiler/token"
+	"errors"
+	"fmt"
+)
+
+// Exception is the error object of try and catch, Runtime errors of the engines become exceptions once a catch
+// block gets them. The kind tells what went wrong, like the name of a JavaScript error. Stack has the frames
+// from where the exception was raised up to the try statement that caught it, the innermost first.
+type Exception struct {
+	Kind    string
+	Message string
+	Stack   []diagnostic.Frame
+}
+
+func (e *Exception) Type() ObjectType { return constants.EXCEPTION_OBJECT }
+func (e *Exception) Inspect() string  { return e.Kind + ": " + e.Message }
+
+// Kinds of the runtime errors, Like the names of the errors of JavaScript. Errors without a kind are of the
+// kind Error.
+const (
+	ErrorKind          = "Error"
+	TypeErrorKind      = "TypeError"
+	RangeErrorKind     = "RangeError"
+	ReferenceErrorKind = "ReferenceError"
+)
+
+// Upper limit on the depth of nested function calls, Shared by both engines so the same recursion overflows in
+// both. Deeper calls raise a RangeError "stack overflow", The main program is not counted.
+const MaxCallDepth = 1024
+
+// Runtime error which is returned back as a Go error, By the virtual machine and by the functions of this
+// package which both engines use. The kind is set where the error is created.
+type KindError struct {
+	Kind    string
+	Message string
+}
+
+func (e *KindError) Error() string { return e.Message }
+
+func TypeErrorf(format string, a ...interface{}) error {
+	return &KindError{Kind: TypeErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+func RangeErrorf(format string, a ...interface{}) error {
+	return &KindError{Kind: RangeErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+func ReferenceErrorf(format string, a ...interface{}) error {
+	return &KindError{Kind: ReferenceErrorKind, Message: fmt.Sprintf(format, a...)}
+}
+
+// Returns back the kind of a Go error, Errors which were not created with a kind are of the kind Error
+func KindOf(err error) string {
+	var kindError *KindError
+	if errors.As(err, &kindError) {
+		return kindError.Kind
+	}
+	return ErrorKind
+}
+
+// Creates the exception of a runtime error of the given kind
+func NewException(kind, message string) *Exception {
+	if kind == "" {
+		kind = ErrorKind
+	}
+	return &Exception{Kind: kind, Message: message}
+}
+
+// Returns back the message of a thrown value that was not caught, Exceptions keep the message they were
+// created with so rethrowing a caught runtime error reports the same message again
+func ThrownMessage(value Object) string {
+	if exception, ok := value.(*Exception); ok {
+		return exception.Message
+	}
+	return "uncaught exception: " + value.Inspect()
+}
+
+// Formats the stack of the exception with one frame per line
+func (e *Exception) StackString() string {
+	return diagnostic.Trace(e.Stack)
+}
+
+// Returns back the name a function has in a stack, Functions which were not bound to a name are anonymous
+func FunctionName(name string) string {
+	if name == "" {
+		return "<anonymous>"
+	}
+	return name
+}
+
+// Records that the error left the function it happened in, The frame of the function gets the name and the
+// code which called it continues the stack at the position of the call.
+func (e *Error) Unwind(function string, call token.Position) {
+	if len(e.Stack) == 0 {
+		e.Stack = []diagnostic.Frame{{Pos: e.Pos}}
+	}
+	e.Stack[len(e.Stack)-1].Function = function
+	e.Stack = append(e.Stack, diagnostic.Frame{Pos: call})
+}
+
+// Returns back the frames of the error with the innermost first, The last frame is the code the error has
+// reached and has no name. An error which did not leave a function has only the frame of its position.
+func (e *Error) Trace() []diagnostic.Frame {
+	if len(e.Stack) == 0 {
+		return []diagnostic.Frame{{Pos: e.Pos}}
+	}
+	return e.Stack
+}
+
+// Returns back the error as a diagnostic at its position along with its stack, Used to report the error
+func (e *Error) Diagnostic() *diagnostic.Diagnostic {
+	return &diagnostic.Diagnostic{Pos: e.Pos, Message: e.Message, Stack: e.Trace()}
+}
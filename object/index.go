@@ -0,0 +1,26 @@
+package object
+
+// Stores the value at the index of an array or under the key of a hash, Arrays and hashes are changed in
+// place so every binding that refers to them sees the new value. Both engines use it for index assignments.
+func SetIndex(left, index, value Object) error {
+	switch left := left.(type) {
+	case *Array:
+		idx, ok := index.(*Integer)
+		if !ok {
+			break
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return RangeErrorf("index out of range: %d", idx.Value)
+		}
+		left.Elements[idx.Value] = value
+		return nil
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return TypeErrorf("unusable as hash key: %s", index.Type())
+		}
+		left.Pairs[key.HashKey()] = HashPair{Key: index, Value: value}
+		return nil
+	}
+	return TypeErrorf("index operator has wrong type that is not supported yet %s", left.Type())
+}
@@ -0,0 +1,143 @@
+package object
+
+import (
+	"compiler/constants"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// Iterator walks over the values of a for each loop, The values are collected when the iterator is created so
+// that changes to the iterated value do not affect a running loop.
+type Iterator struct {
+	values []Object
+	pos    int
+}
+
+func (it *Iterator) Type() ObjectType { return constants.ITERATOR_OBJECT }
+func (it *Iterator) Inspect() string  { return "iterator" }
+
+// Returns back the next value and false once every value has been returned
+func (it *Iterator) Next() (Object, bool) {
+	if it.pos >= len(it.values) {
+		return nil, false
+	}
+	value := it.values[it.pos]
+	it.pos++
+	return value, true
+}
+
+// Creates the iterator of a for each loop, With keys the iterator returns back the indexes of arrays and
+// strings and the keys of hashes, as `for (x in value)` does. Otherwise it returns back the elements of arrays,
+// the characters of strings and the values of hashes, as `for (x of value)` does. Hashes are iterated in the
+// order of their sorted keys.
+func NewIterator(obj Object, keys bool) (*Iterator, error) {
+	values := []Object{}
+	switch obj := obj.(type) {
+	case *Array:
+		for i, element := range obj.Elements {
+			if keys {
+				values = append(values, &Integer{Value: int64(i)})
+			} else {
+				values = append(values, element)
+			}
+		}
+	case *String:
+		for i, char := range []rune(obj.Value) {
+			if keys {
+				values = append(values, &Integer{Value: int64(i)})
+			} else {
+				values = append(values, &String{Value: string(char)})
+			}
+		}
+	case *Hash:
+		for _, pair := range obj.SortedPairs() {
+			if keys {
+				values = append(values, pair.Key)
+			} else {
+				values = append(values, pair.Value)
+			}
+		}
+	default:
+		return nil, TypeErrorf("%s is not iterable", obj.Type())
+	}
+	return &Iterator{values: values}, nil
+}
+
+// Returns back the pairs of the hash sorted by their keys, Keys of the same type are ordered by value and
+// keys of different types by the name of their type.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+	return pairs
+}
+
+// Orders hash keys so that iteration and Inspect are deterministic, Numbers of every kind are ordered by
+// their value and come before or after other keys as one group
+func lessKey(a, b Object) bool {
+	if isNumericKey(a) && isNumericKey(b) {
+		if c := compareNumericKeys(a, b); c != 0 {
+			return c < 0
+		}
+		return a.Type() < b.Type()
+	}
+	if keyGroup(a) != keyGroup(b) {
+		return keyGroup(a) < keyGroup(b)
+	}
+	switch a := a.(type) {
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	}
+	return a.Inspect() < b.Inspect()
+}
+
+func isNumericKey(obj Object) bool {
+	return IsNumber(obj) || IsInteger(obj)
+}
+
+// Returns back the type a key is grouped by when sorted, Integers, big integers and floats share a group
+func keyGroup(obj Object) ObjectType {
+	if isNumericKey(obj) {
+		return constants.INTEGER_OBJECT
+	}
+	return obj.Type()
+}
+
+// Compares two numbers of any kind by their exact value, NaN is greater than every other number
+func compareNumericKeys(a, b Object) int {
+	aNaN, bNaN := isNaN(a), isNaN(b)
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return 1
+	case bNaN:
+		return -1
+	}
+	return exactValue(a).Cmp(exactValue(b))
+}
+
+func isNaN(obj Object) bool {
+	f, ok := obj.(*Float)
+	return ok && math.IsNaN(f.Value)
+}
+
+// Returns back the value of an integer, a big integer or a float which is not NaN without rounding
+func exactValue(obj Object) *big.Float {
+	switch obj := obj.(type) {
+	case *Integer:
+		return new(big.Float).SetInt64(obj.Value)
+	case *BigInt:
+		return new(big.Float).SetInt(obj.Value)
+	case *Float:
+		return new(big.Float).SetFloat64(obj.Value)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package object
+
+import (
+	"compiler/constants"
+	"strings"
+)
+
+// Method of a builtin type, The receiver is the value left of the dot in `value.method(args)`
+type method func(receiver Object, args ...Object) Object
+
+// Methods of strings and arrays, Like the builtins they return back nil when there is no value.
+// Arrays are changed in place by push and pop, the same way they are in JavaScript.
+var methods = map[ObjectType]map[string]method{
+	constants.STRING_OBJECT: {
+		"toUpperCase": func(receiver Object, args ...Object) Object {
+			if len(args) != 0 {
+				return newTypeError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return &String{Value: strings.ToUpper(receiver.(*String).Value)}
+		},
+		"toLowerCase": func(receiver Object, args ...Object) Object {
+			if len(args) != 0 {
+				return newTypeError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return &String{Value: strings.ToLower(receiver.(*String).Value)}
+		},
+		"trim": func(receiver Object, args ...Object) Object {
+			if len(args) != 0 {
+				return newTypeError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			return &String{Value: strings.TrimSpace(receiver.(*String).Value)}
+		},
+		// Splits the string around the separator into an array of strings
+		"split": func(receiver Object, args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			sep, ok := args[0].(*String)
+			if !ok {
+				return newTypeError("argument to split is invalid. got=%s", args[0].Type())
+			}
+			parts := strings.Split(receiver.(*String).Value, sep.Value)
+			elements := make([]Object, len(parts))
+			for i, part := range parts {
+				elements[i] = &String{Value: part}
+			}
+			return &Array{Elements: elements}
+		},
+		// Returns back the index of the first occurrence of the substring or -1
+		"indexOf": func(receiver Object, args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			sub, ok := args[0].(*String)
+			if !ok {
+				return newTypeError("argument to indexOf is invalid. got=%s", args[0].Type())
+			}
+			return &Integer{Value: int64(strings.Index(receiver.(*String).Value, sub.Value))}
+		},
+	},
+	constants.ARRAY_OBJECT: {
+		// Appends the elements to the end of the array and returns back the new length
+		"push": func(receiver Object, args ...Object) Object {
+			arr := receiver.(*Array)
+			arr.Elements = append(arr.Elements, args...)
+			return &Integer{Value: int64(len(arr.Elements))}
+		},
+		// Removes the last element of the array and returns it back
+		"pop": func(receiver Object, args ...Object) Object {
+			if len(args) != 0 {
+				return newTypeError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			arr := receiver.(*Array)
+			length := len(arr.Elements)
+			if length == 0 {
+				return nil
+			}
+			last := arr.Elements[length-1]
+			arr.Elements = arr.Elements[:length-1]
+			return last
+		},
+		// Joins the inspected elements with the separator, The separator defaults to a comma
+		"join": func(receiver Object, args ...Object) Object {
+			if len(args) > 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			sep := ","
+			if len(args) == 1 {
+				s, ok := args[0].(*String)
+				if !ok {
+					return newTypeError("argument to join is invalid. got=%s", args[0].Type())
+				}
+				sep = s.Value
+			}
+			parts := []string{}
+			for _, element := range receiver.(*Array).Elements {
+				parts = append(parts, element.Inspect())
+			}
+			return &String{Value: strings.Join(parts, sep)}
+		},
+		// Returns back the index of the first element equal to the argument or -1, Only values that can be
+		// hash keys are compared
+		"indexOf": func(receiver Object, args ...Object) Object {
+			if len(args) != 1 {
+				return newTypeError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			target, ok := args[0].(Hashable)
+			if !ok {
+				return &Integer{Value: -1}
+			}
+			for i, element := range receiver.(*Array).Elements {
+				if key, ok := element.(Hashable); ok && key.HashKey() == target.HashKey() {
+					return &Integer{Value: int64(i)}
+				}
+			}
+			return &Integer{Value: -1}
+		},
+		// Copies the elements from start up to but not including end into a new array
+		"slice": func(receiver Object, args ...Object) Object {
+			if len(args) > 2 {
+				return newTypeError("wrong number of arguments. got=%d, want=0 to 2", len(args))
+			}
+			elements := receiver.(*Array).Elements
+			bounds := []int64{0, int64(len(elements))}
+			for i, arg := range args {
+				integer, ok := arg.(*Integer)
+				if !ok {
+					return newTypeError("argument to slice is invalid. got=%s", arg.Type())
+				}
+				bounds[i] = clampIndex(integer.Value, int64(len(elements)))
+			}
+			if bounds[0] > bounds[1] {
+				bounds[0] = bounds[1]
+			}
+			sliced := make([]Object, bounds[1]-bounds[0])
+			copy(sliced, elements[bounds[0]:bounds[1]])
+			return &Array{Elements: sliced}
+		},
+	},
+}
+
+// Negative indexes count from the end, Indexes outside of the length are moved to the closest bound
+func clampIndex(index, length int64) int64 {
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
+// Reports whether the value has properties that are read with GetMember, Hashes are indexed by their keys instead
+func HasMembers(obj Object) bool {
+	switch obj.(type) {
+	case *String, *Array, *Exception:
+		return true
+	}
+	return false
+}
+
+// Returns back the property of a string, an array or an exception for `value.name`, Methods are returned back
+// as builtins bound to the value so that `arr.push` can be called later. Both engines use it for member access.
+func GetMember(obj Object, name string) (Object, error) {
+	if exception, ok := obj.(*Exception); ok {
+		switch name {
+		case "message":
+			return &String{Value: exception.Message}, nil
+		case "kind":
+			return &String{Value: exception.Kind}, nil
+		case "stack":
+			return &String{Value: exception.StackString()}, nil
+		}
+	}
+	if name == "length" {
+		switch obj := obj.(type) {
+		case *String:
+			return &Integer{Value: int64(len(obj.Value))}, nil
+		case *Array:
+			return &Integer{Value: int64(len(obj.Elements))}, nil
+		}
+	}
+	fn, ok := methods[obj.Type()][name]
+	if !ok {
+		return nil, TypeErrorf("%s has no property %s", obj.Type(), name)
+	}
+	return &Builtin{Fn: func(args ...Object) Object {
+		return fn(obj, args...)
+	}}, nil
+}
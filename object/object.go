@@ -3,9 +3,14 @@ package object
 import (
 	"bytes"
 	"compiler/ast"
+	"compiler/code"
 	"compiler/constants"
+	"compiler/diagnostic"
+	"compiler/token"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -29,6 +34,10 @@ type Integer struct {
 	Value int64
 }
 
+type Float struct {
+	Value float64
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -37,8 +46,21 @@ type ReturnValue struct {
 	Value Object
 }
 
+// Break and Continue are passed up from the statement to the enclosing loop of the evaluator, like ReturnValue
+// is passed up to the enclosing function.
+type Break struct{}
+
+type Continue struct{}
+
+// Error is the runtime error of the evaluator, Pos is the position of the node which produced the error.
+// Errors of throw statements carry the thrown value, It is what the catch block gets back. Stack has the
+// frames of the functions the error left, It is filled in by Unwind.
 type Error struct {
+	Kind    string // Kind of the exception a catch block gets, Like TypeError
 	Message string
+	Pos     token.Position
+	Thrown  Object
+	Stack   []diagnostic.Frame
 }
 
 type Null struct {
@@ -48,10 +70,58 @@ type String struct {
 	Value string
 }
 
+// Function of the evaluator, Name is the name it was bound with and is empty for anonymous functions
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Enviornment
+	Name       string
+}
+
+// Compiled function is the bytecode of a function literal, It is stored in the constant pool and wrapped
+// into a closure at runtime. Handlers is the exception handler table of its try statements and Positions
+// maps its instructions back to the source.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	Name          string
+	Handlers      []code.Handler
+	Positions     code.SourceMap
+}
+
+// Cell holds a local variable of the virtual machine once a closure captured it, The function that declared
+// the variable and every closure that captured it share the cell so assignments are seen by all of them.
+// Cells are never values of the program, The virtual machine unwraps them when the variable is read.
+type Cell struct {
+	Value Object
+}
+
+// Uninitialized is the value of a name of a block with function declarations until its let statement runs,
+// The virtual machine raises a ReferenceError when the name is read or assigned to before that. Like cells it is
+// never a value of the program.
+type Uninitialized struct {
+	Name string
+}
+
+// Quote holds source code which was not evaluated, It is what quote returns back and what macros get as
+// arguments and return back.
+type Quote struct {
+	Node ast.Node
+}
+
+// Macro is bound by a top level let statement and is expanded before the program runs
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Enviornment
+}
+
+// Closure is the function value of the virtual machine, It has the compiled function along with the
+// values of the free variables captured when the closure was created.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
 }
 
 type Builtin struct {
@@ -79,6 +149,25 @@ type HashPair struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return constants.INTEGER_OBJECT }
 
+// Floats are printed the way JavaScript prints numbers, Whole numbers have no fraction digits and
+// very large or very small values use the exponent notation.
+func (f *Float) Inspect() string {
+	abs := math.Abs(f.Value)
+	switch {
+	case math.IsNaN(f.Value):
+		return "NaN"
+	case math.IsInf(f.Value, 1):
+		return "Infinity"
+	case math.IsInf(f.Value, -1):
+		return "-Infinity"
+	case abs == 0 || (abs >= 1e-6 && abs < 1e21):
+		return strconv.FormatFloat(f.Value, 'f', -1, 64)
+	default:
+		return strconv.FormatFloat(f.Value, 'g', -1, 64)
+	}
+}
+func (f *Float) Type() ObjectType { return constants.FLOAT_OBJECT }
+
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 func (b *Boolean) Type() ObjectType { return constants.BOOLEAN_OBJECT }
 
@@ -88,6 +177,12 @@ func (n *Null) Type() ObjectType { return constants.NULL_OBJECT }
 func (rv *ReturnValue) Type() ObjectType { return constants.RETURN_VALUE_OBJECT }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+func (b *Break) Type() ObjectType { return constants.BREAK_OBJECT }
+func (b *Break) Inspect() string  { return "break" }
+
+func (c *Continue) Type() ObjectType { return constants.CONTINUE_OBJECT }
+func (c *Continue) Inspect() string  { return "continue" }
+
 func (e *Error) Type() ObjectType { return constants.ERROR_OBJECT }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
@@ -107,6 +202,43 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+func (cf *CompiledFunction) Type() ObjectType { return constants.COMPILED_FUNCTION }
+func (cf *CompiledFunction) Inspect() string  { return fmt.Sprintf("CompiledFunction[%p]", cf) }
+
+// Closures report the same type as evaluator functions so that both engines produce the same error messages
+func (c *Closure) Type() ObjectType { return constants.FUNCTION_OBJ }
+func (c *Closure) Inspect() string {
+	if c.Fn.Name != "" {
+		return fmt.Sprintf("Closure<%s>[%p]", c.Fn.Name, c)
+	}
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+func (q *Quote) Type() ObjectType { return constants.QUOTE_OBJECT }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+func (m *Macro) Type() ObjectType { return constants.MACRO_OBJECT }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
+
+func (c *Cell) Type() ObjectType { return constants.CELL_OBJECT }
+func (c *Cell) Inspect() string  { return "cell(" + c.Value.Inspect() + ")" }
+
+func (u *Uninitialized) Type() ObjectType { return constants.UNINITIALIZED_OBJ }
+func (u *Uninitialized) Inspect() string  { return "uninitialized(" + u.Name + ")" }
+
 func (s *String) Type() ObjectType { return constants.STRING_OBJECT }
 func (s *String) Inspect() string  { return s.Value }
 
@@ -140,6 +272,15 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// Whole floats hash like the integer they are equal to so 2 and 2.0 are the same key, That also makes 0.0 and
+// -0.0 the same key
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return (&Integer{Value: int64(f.Value)}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -151,7 +292,7 @@ func (h *Hash) Type() ObjectType { return constants.HASH_OBJECT }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.SortedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 	out.WriteString("{")
@@ -159,3 +300,24 @@ func (h *Hash) Inspect() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+// Checks if the object is an integer or a float
+func IsNumber(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *Float:
+		return true
+	}
+	return false
+}
+
+// Returns back the numeric value of an integer or a float as float64, Used when integers are promoted
+// to floats in mixed arithmetic.
+func ToFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *Float:
+		return obj.Value
+	}
+	return math.NaN()
+}
@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +22,220 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	one1 := &Float{Value: 1.5}
+	one2 := &Float{Value: 1.5}
+	two := &Float{Value: 2.5}
+	if one1.HashKey() != one2.HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+	if one1.HashKey() == two.HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+	if (&Float{Value: 2}).HashKey() != (&Integer{Value: 2}).HashKey() {
+		t.Errorf("whole float has a different hash key than the integer of the same value")
+	}
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+	if (&Float{Value: math.Pow(2, 63)}).HashKey() == (&Integer{Value: math.MinInt64}).HashKey() {
+		t.Errorf("float out of the integer range has the hash key of an integer")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{3, "3"},
+		{-0.5, "-0.5"},
+		{1000000, "1000000"},
+		{1e21, "1e+21"},
+		{0.0000001, "1e-07"},
+		{math.Inf(1), "Infinity"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("wrong inspect for %v. want=%q, got=%q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestGetMember(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}}}
+	length, err := GetMember(arr, "length")
+	if err != nil || length.(*Integer).Value != 1 {
+		t.Fatalf("wrong length. got=%v err=%v", length, err)
+	}
+	push, err := GetMember(arr, "push")
+	if err != nil {
+		t.Fatalf("push not found: %s", err)
+	}
+	push.(*Builtin).Fn(&Integer{Value: 2})
+	if len(arr.Elements) != 2 {
+		t.Errorf("push did not change the receiver. got=%s", arr.Inspect())
+	}
+	if _, err := GetMember(&Integer{Value: 1}, "length"); err == nil || err.Error() != "INTEGER has no property length" {
+		t.Errorf("wrong error for missing property. got=%v", err)
+	}
+}
+
+func TestNewException(t *testing.T) {
+	_, member := GetMember(&Array{}, "size")
+	_, iterate := NewIterator(&Integer{Value: 1}, false)
+	_, divide := IntegerArithmetic("/", 1, 0)
+	tests := []struct {
+		err  error
+		kind string
+	}{
+		{member, "TypeError"},
+		{iterate, "TypeError"},
+		{divide, "RangeError"},
+		{SetIndex(&Array{}, &Integer{Value: 5}, &Integer{Value: 1}), "RangeError"},
+		{ReferenceErrorf("identifier not found: x"), "ReferenceError"},
+		{fmt.Errorf("something else"), "Error"},
+	}
+	for _, tt := range tests {
+		exception := NewException(KindOf(tt.err), tt.err.Error())
+		if exception.Kind != tt.kind || exception.Message != tt.err.Error() {
+			t.Errorf("wrong exception for %q. got=%s", tt.err, exception.Inspect())
+		}
+	}
+	if builtin := NewException(newTypeError("bad").Kind, "bad"); builtin.Kind != "TypeError" {
+		t.Errorf("wrong kind of builtin error. got=%s", builtin.Kind)
+	}
+	if plain := NewException("", "bad"); plain.Kind != "Error" {
+		t.Errorf("wrong kind without a kind. got=%s", plain.Kind)
+	}
+	exception := &Exception{Kind: "TypeError", Message: "bad"}
+	for name, expected := range map[string]string{"kind": "TypeError", "message": "bad", "stack": ""} {
+		member, err := GetMember(exception, name)
+		if err != nil || member.(*String).Value != expected {
+			t.Errorf("wrong %s. got=%v err=%v", name, member, err)
+		}
+	}
+	if ThrownMessage(&Integer{Value: 1}) != "uncaught exception: 1" || ThrownMessage(exception) != "bad" {
+		t.Errorf("wrong thrown messages. got=%q and %q", ThrownMessage(&Integer{Value: 1}), ThrownMessage(exception))
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	defer func(policy OverflowPolicy) { Overflow = policy }(Overflow)
+	tests := []struct {
+		policy   OverflowPolicy
+		operator string
+		left     int64
+		right    int64
+		expected string
+	}{
+		{OverflowWrap, "+", 1, 2, "3"},
+		{OverflowWrap, "/", 7, 2, "3"},
+		{OverflowWrap, "/", 7, 0, "division by zero"},
+		{OverflowWrap, "+", math.MaxInt64, 1, "-9223372036854775808"},
+		{OverflowWrap, "/", math.MinInt64, -1, "-9223372036854775808"},
+		{OverflowError, "+", math.MaxInt64, 1, "integer overflow: 9223372036854775807 + 1"},
+		{OverflowError, "-", math.MinInt64, 1, "integer overflow: -9223372036854775808 - 1"},
+		{OverflowError, "*", -1, math.MinInt64, "integer overflow: -1 * -9223372036854775808"},
+		{OverflowError, "*", math.MaxInt64, -1, "-9223372036854775807"},
+		{OverflowError, "/", 7, 0, "division by zero"},
+		{OverflowPromote, "+", math.MaxInt64, 1, "9223372036854775808n"},
+		{OverflowPromote, "*", math.MaxInt64, math.MaxInt64, "85070591730234615847396907784232501249n"},
+		{OverflowPromote, "/", math.MinInt64, -1, "9223372036854775808n"},
+		{OverflowPromote, "-", 5, 7, "-2"},
+		{OverflowError, "%", math.MinInt64, -1, "0"},
+		{OverflowError, "%", 7, 0, "division by zero"},
+		{OverflowError, "**", -2, 63, "-9223372036854775808"},
+		{OverflowError, "**", 2, 63, "integer overflow: 2 ** 63"},
+		{OverflowError, "**", 2, -2, "0.25"},
+		{OverflowWrap, "**", 3, 41, "-420491770248316829"},
+		{OverflowPromote, "**", 3, 41, "36472996377170786403n"},
+		{OverflowError, "<<", 1, 63, "-9223372036854775808"},
+		{OverflowError, "<<", 1, 65, "2"},
+		{OverflowError, ">>>", -1, 1, "9223372036854775807"},
+	}
+	for _, tt := range tests {
+		Overflow = tt.policy
+		result, err := IntegerArithmetic(tt.operator, tt.left, tt.right)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = result.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%d %s %d with %s policy: want=%s, got=%s", tt.left, tt.operator, tt.right, tt.policy, tt.expected, got)
+		}
+	}
+}
+
+func TestNegateInteger(t *testing.T) {
+	defer func(policy OverflowPolicy) { Overflow = policy }(Overflow)
+	expected := map[OverflowPolicy]string{
+		OverflowWrap:    "-9223372036854775808",
+		OverflowError:   "integer overflow: -(-9223372036854775808)",
+		OverflowPromote: "9223372036854775808n",
+	}
+	for policy, want := range expected {
+		Overflow = policy
+		result, err := NegateInteger(math.MinInt64)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = result.Inspect()
+		}
+		if got != want {
+			t.Errorf("negation with %s policy: want=%s, got=%s", policy, want, got)
+		}
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	big1 := &BigInt{Value: big.NewInt(42)}
+	big2 := &BigInt{Value: big.NewInt(42)}
+	other := &BigInt{Value: big.NewInt(43)}
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+	if big1.HashKey() == other.HashKey() {
+		t.Errorf("big integers with different values have same hash keys")
+	}
+	if big1.HashKey() == (&Integer{Value: 42}).HashKey() {
+		t.Errorf("big integer has the hash key of the integer of the same value")
+	}
+}
+
+func TestSortedPairsMixedNumbers(t *testing.T) {
+	keys := []Hashable{
+		&BigInt{Value: big.NewInt(3)},
+		&String{Value: "a"},
+		&Float{Value: 1.5},
+		&Integer{Value: 2},
+		&Float{Value: math.NaN()},
+		&Boolean{Value: true},
+		&Integer{Value: 3},
+		&Float{Value: math.Inf(-1)},
+		&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)},
+		&Integer{Value: 1<<53 + 1},
+		&Float{Value: 1 << 53},
+	}
+	expected := "{true: 5, -Infinity: 7, 1.5: 2, 2: 3, 3n: 0, 3: 6, 9007199254740992: 10, 9007199254740993: 9, " +
+		"1180591620717411303424n: 8, NaN: 4, a: 1}"
+
+	// Every order of insertion has to give the same order of the keys
+	for shift := range keys {
+		hash := &Hash{Pairs: map[HashKey]HashPair{}}
+		for i := range keys {
+			index := (i + shift) % len(keys)
+			key := keys[index]
+			hash.Pairs[key.HashKey()] = HashPair{Key: key.(Object), Value: &Integer{Value: int64(index)}}
+		}
+		if hash.Inspect() != expected {
+			t.Errorf("wrong order of keys when inserted from %d. want=%s, got=%s", shift, expected, hash.Inspect())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"compiler/ast"
+	"compiler/constants"
+)
+
+// Assignment is right associative, So the value is parsed with a lower precedence than the assignment
+// itself and `a = b = 1` assigns 1 to both.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{Token: p.curToken, Operator: p.curToken.Literal, Target: target}
+	if !p.checkAssignmentTarget(target) {
+		return &ast.BadExpression{Token: p.curToken}
+	}
+	p.nextToken()
+	expression.Value = p.parseExpression(constants.ASSIGN - 1)
+	return expression
+}
+
+func (p *Parser) parsePrefixUpdateExpression() ast.Expression {
+	expression := &ast.UpdateExpression{Token: p.curToken, Operator: p.curToken.Literal, Prefix: true}
+	p.nextToken()
+	expression.Target = p.parseExpression(constants.PREFIX)
+	if !p.checkAssignmentTarget(expression.Target) {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+	return expression
+}
+
+func (p *Parser) parsePostfixUpdateExpression(target ast.Expression) ast.Expression {
+	expression := &ast.UpdateExpression{Token: p.curToken, Operator: p.curToken.Literal, Target: target}
+	if !p.checkAssignmentTarget(target) {
+		return &ast.BadExpression{Token: p.curToken}
+	}
+	return expression
+}
+
+// Only names and index expressions can be assigned to, Anything else is reported as an error
+func (p *Parser) checkAssignmentTarget(target ast.Expression) bool {
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+		return true
+	case *ast.BadExpression:
+		// The broken target has already been reported
+		return false
+	}
+	p.addError(p.curToken, nil, "invalid assignment target %s", target.String())
+	return false
+}
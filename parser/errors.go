@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"compiler/diagnostic"
+	"compiler/token"
+)
+
+// ParseError is a syntax error found by the parser, Tooling can use the expected and found tokens to offer
+// fixes while the message is meant for people.
+type ParseError struct {
+	Pos      token.Position
+	Message  string
+	Expected []token.Type // Tokens that would have been accepted, Empty when the error is not about a missing token
+	Found    token.Token  // Token found where the error was detected
+}
+
+// Error returns back the message prefixed by the position
+func (e *ParseError) Error() string {
+	return e.Diagnostic().Error()
+}
+
+// Returns back the error as diagnostic, Used for rendering the error along with the source excerpt
+func (e *ParseError) Diagnostic() *diagnostic.Diagnostic {
+	return &diagnostic.Diagnostic{Pos: e.Pos, Message: e.Message}
+}
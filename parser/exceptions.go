@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"compiler/ast"
+	"compiler/constants"
+	"compiler/token"
+)
+
+// Parses `throw value`
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Value = p.parseExpression(constants.LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// Parses `try { } catch (e) { } finally { }`, The parameter of the catch block is declared in the scope of the
+// catch block. Either the catch or the finally block can be left out but not both of them.
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return &ast.BadStatement{Token: stmt.Token}
+	}
+	stmt.Block = p.parseBlockStatement()
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.parseCatch(stmt) {
+			return &ast.BadStatement{Token: stmt.Token}
+		}
+	}
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return &ast.BadStatement{Token: stmt.Token}
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addError(p.peekToken, []token.Type{token.CATCH, token.FINALLY}, "Expected next token is %s or %s we got %s", token.CATCH, token.FINALLY, p.peekToken.Type)
+		return &ast.BadStatement{Token: stmt.Token}
+	}
+	return stmt
+}
+
+// Parses the rest of the catch block, The current token is catch
+func (p *Parser) parseCatch(stmt *ast.TryStatement) bool {
+	p.enterScope()
+	defer p.leaveScope()
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.declare(stmt.Param)
+		if !p.expectPeek(token.RPAREN) {
+			return false
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return false
+	}
+	stmt.Catch = p.parseBlock()
+	return true
+}
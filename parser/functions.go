@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"compiler/ast"
+	"compiler/constants"
+	"compiler/token"
+)
+
+// Function declaration `function name(params) { body }`, The name is declared before the body is parsed
+// because declarations are hoisted to the top of their block.
+func (p *Parser) parseFunctionDeclaration() ast.Statement {
+	stmt := &ast.FunctionDeclaration{Token: p.curToken}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.declare(stmt.Name)
+	lit := &ast.FunctionLiteral{Token: stmt.Token, Name: stmt.Name.Value}
+	if !p.parseFunction(lit) {
+		return &ast.BadStatement{Token: stmt.Token}
+	}
+	stmt.Function = lit
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.parseFunction(lit) {
+		return &ast.BadExpression{Token: lit.Token}
+	}
+	return lit
+}
+
+// Parses the parameters and the body of a function, The current token is the one before the parameters
+func (p *Parser) parseFunction(lit *ast.FunctionLiteral) bool {
+	if !p.expectPeek(token.LPAREN) {
+		return false
+	}
+	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil || !p.expectPeek(token.LBRACE) {
+		return false
+	}
+	p.parseFunctionBody(lit, func() *ast.BlockStatement { return p.parseBlock() })
+	return true
+}
+
+// Loops around the function can not be left with break or continue from inside the body, And the parameters
+// share the scope of the body so the body can not declare a name of a parameter again
+func (p *Parser) parseFunctionBody(lit *ast.FunctionLiteral, parseBody func() *ast.BlockStatement) {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.enterScope()
+	for _, param := range lit.Parameters {
+		p.declare(param)
+	}
+	lit.Body = parseBody()
+	p.leaveScope()
+	p.loopDepth = loopDepth
+}
+
+// Macro literals are parsed like function literals, The body is only run when the macro is expanded
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	fn := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.parseFunction(fn) {
+		return &ast.BadExpression{Token: fn.Token}
+	}
+	return &ast.MacroLiteral{Token: fn.Token, Parameters: fn.Parameters, Body: fn.Body}
+}
+
+// Arrow function with a single parameter without parentheses `x => body`, The current token is the arrow
+func (p *Parser) parseArrowFunction(left ast.Expression) ast.Expression {
+	return p.parseArrowBody(p.curToken, []ast.Expression{left})
+}
+
+// Parentheses start either a grouped expression or the parameters of an arrow function, Which one it is
+// is known at the closing parenthesis: `()` and lists with commas have to be followed by an arrow.
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	start := p.curToken
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.ARROW) {
+			return &ast.BadExpression{Token: start}
+		}
+		return p.parseArrowBody(p.curToken, []ast.Expression{})
+	}
+	p.nextToken()
+	expressions := []ast.Expression{p.parseExpression(constants.LOWEST)}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		expressions = append(expressions, p.parseExpression(constants.LOWEST))
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return &ast.BadExpression{Token: start}
+	}
+	if len(expressions) == 1 && !p.peekTokenIs(token.ARROW) {
+		return expressions[0]
+	}
+	if !p.expectPeek(token.ARROW) {
+		return &ast.BadExpression{Token: start}
+	}
+	return p.parseArrowBody(p.curToken, expressions)
+}
+
+// Parses the body after the arrow, A body that is not a block is a single expression which is the value
+// of the function
+func (p *Parser) parseArrowBody(arrow token.Token, params []ast.Expression) ast.Expression {
+	lit := &ast.FunctionLiteral{Token: arrow, Parameters: []*ast.Identifier{}}
+	for _, param := range params {
+		ident, ok := param.(*ast.Identifier)
+		if !ok {
+			p.addError(arrow, nil, "invalid arrow function parameter %s", param.String())
+			return &ast.BadExpression{Token: arrow}
+		}
+		lit.Parameters = append(lit.Parameters, ident)
+	}
+	p.parseFunctionBody(lit, func() *ast.BlockStatement {
+		p.nextToken()
+		if p.curTokenIs(token.LBRACE) {
+			return p.parseBlock()
+		}
+		body := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(constants.LOWEST)}
+		return &ast.BlockStatement{Token: body.Token, Statements: []ast.Statement{body}}
+	})
+	return lit
+}
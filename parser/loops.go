@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"compiler/ast"
+	"compiler/constants"
+	"compiler/token"
+)
+
+// Parses `while (condition) { body }`
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return &ast.BadStatement{Token: stmt.Token}
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(constants.LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return &ast.BadStatement{Token: stmt.Token}
+	}
+	body := p.parseLoopBody()
+	if body == nil {
+		return &ast.BadStatement{Token: stmt.Token}
+	}
+	stmt.Body = body
+	return stmt
+}
+
+// Parses both kinds of for loops, The header decides which one it is. A name followed by `of` or `in` starts
+// a for each loop and anything else is the init statement of a C style for loop. Names declared in the
+// header belong to the scope of the loop which encloses the scope of the body.
+func (p *Parser) parseForStatement() ast.Statement {
+	p.enterScope()
+	defer p.leaveScope()
+	forToken := p.curToken
+	if !p.expectPeek(token.LPAREN) {
+		return &ast.BadStatement{Token: forToken}
+	}
+	p.nextToken()
+	var init ast.Statement
+	switch {
+	case p.curTokenIs(token.SEMICOLON):
+	case (p.curTokenIs(token.LET) || p.curTokenIs(token.CONST)) && p.peekTokenIs(token.IDENT):
+		letToken := p.curToken
+		p.nextToken()
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekIsForEachOperator() {
+			return p.parseForEachStatement(forToken, name, letToken.Type == token.CONST)
+		}
+		init = p.parseLetValue(&ast.LetStatement{Token: letToken, Name: name})
+	case p.curTokenIs(token.IDENT) && p.peekIsForEachOperator():
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		return p.parseForEachStatement(forToken, name, false)
+	default:
+		init = p.parseStatement()
+	}
+	// The init statement has consumed its semicolon, so the current token is the first semicolon
+	if !p.curTokenIs(token.SEMICOLON) {
+		p.addError(p.peekToken, []token.Type{token.SEMICOLON}, "Expected next token is %s we got %s", token.SEMICOLON, p.peekToken.Type)
+		return &ast.BadStatement{Token: forToken}
+	}
+	stmt := &ast.ForStatement{Token: forToken, Init: init}
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(constants.LOWEST)
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return &ast.BadStatement{Token: forToken}
+	}
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Update = p.parseForUpdate()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return &ast.BadStatement{Token: forToken}
+	}
+	body := p.parseLoopBody()
+	if body == nil {
+		return &ast.BadStatement{Token: forToken}
+	}
+	stmt.Body = body
+	return stmt
+}
+
+// The update of a for loop is an expression, It is not followed by a semicolon
+func (p *Parser) parseForUpdate() ast.Statement {
+	return &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(constants.LOWEST)}
+}
+
+func (p *Parser) peekIsForEachOperator() bool {
+	return p.peekTokenIs(token.IN) || (p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "of")
+}
+
+// Parses the rest of `for (name of value) { body }` or `for (name in value) { body }`, The current token is
+// the name of the loop variable
+func (p *Parser) parseForEachStatement(forToken token.Token, name *ast.Identifier, constant bool) ast.Statement {
+	p.nextToken()
+	stmt := &ast.ForEachStatement{Token: forToken, Variable: name, Constant: constant, Operator: p.curToken.Literal}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(constants.LOWEST)
+	p.declare(name)
+	if !p.expectPeek(token.RPAREN) {
+		return &ast.BadStatement{Token: forToken}
+	}
+	body := p.parseLoopBody()
+	if body == nil {
+		return &ast.BadStatement{Token: forToken}
+	}
+	stmt.Body = body
+	return stmt
+}
+
+// Parses the block of a loop, break and continue are only allowed inside of it
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.loopDepth++
+	body := p.parseBlockStatement()
+	p.loopDepth--
+	return body
+}
+
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+	if p.loopDepth == 0 {
+		p.reportError(p.curToken, "%s is only allowed inside a loop", p.curToken.Literal)
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
@@ -3,11 +3,14 @@ package parser
 import (
 	"compiler/ast"
 	"compiler/constants"
+	"compiler/diagnostic"
 	"compiler/lexer"
 	"compiler/token"
+	"errors"
 	"fmt"
-
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 /*
@@ -17,16 +20,37 @@ import (
 */
 
 var precedence = map[token.Type]int{
-	token.EQ:       constants.EQUALS,
-	token.NEQ:      constants.EQUALS,
-	token.LT:       constants.LESSGREATER,
-	token.GT:       constants.LESSGREATER,
-	token.PLUS:     constants.SUM,
-	token.MINUS:    constants.SUM,
-	token.SLASH:    constants.PRODUCT,
-	token.ASTARISK: constants.PRODUCT,
-	token.LPAREN:   constants.CALL,
-	token.LBRACKET: constants.INDEX,
+	token.ASSIGN:          constants.ASSIGN,
+	token.PLUS_ASSIGN:     constants.ASSIGN,
+	token.MINUS_ASSIGN:    constants.ASSIGN,
+	token.ASTARISK_ASSIGN: constants.ASSIGN,
+	token.SLASH_ASSIGN:    constants.ASSIGN,
+	token.INCREMENT:       constants.POSTFIX,
+	token.DECREMENT:       constants.POSTFIX,
+	token.OR:              constants.LOGICALOR,
+	token.AND:             constants.LOGICALAND,
+	token.PIPE:            constants.BITOR,
+	token.CARET:           constants.BITXOR,
+	token.AMPERSAND:       constants.BITAND,
+	token.EQ:              constants.EQUALS,
+	token.NEQ:             constants.EQUALS,
+	token.LT:              constants.LESSGREATER,
+	token.GT:              constants.LESSGREATER,
+	token.LE:              constants.LESSGREATER,
+	token.GE:              constants.LESSGREATER,
+	token.LSHIFT:          constants.SHIFT,
+	token.RSHIFT:          constants.SHIFT,
+	token.URSHIFT:         constants.SHIFT,
+	token.PLUS:            constants.SUM,
+	token.MINUS:           constants.SUM,
+	token.SLASH:           constants.PRODUCT,
+	token.ASTARISK:        constants.PRODUCT,
+	token.PERCENT:         constants.PRODUCT,
+	token.POWER:           constants.EXPONENT,
+	token.LPAREN:          constants.CALL,
+	token.LBRACKET:        constants.INDEX,
+	token.DOT:             constants.INDEX,
+	token.ARROW:           constants.ASSIGN,
 }
 
 type (
@@ -34,17 +58,37 @@ type (
 	infixParsingFunction  func(ast.Expression) ast.Expression
 )
 
+// Tokens which start a statement, The parser synchronizes on them after an error
+var statementKeywords = map[token.Type]bool{
+	token.LET:      true,
+	token.CONST:    true,
+	token.RETURN:   true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.FUNCTION: true,
+	token.THROW:    true,
+	token.TRY:      true,
+}
+
+// The parser recovers from errors in panic mode, After the first error of a statement every further error
+// is suppressed and the tokens are skipped up to the end of the statement. Broken parts of the source are
+// kept in the tree as bad expressions and statements so that the program can still be inspected.
 type Parser struct {
 	l                     lexer.Lexer
 	curToken              token.Token
 	peekToken             token.Token
-	errors                []string
+	errors                []*ParseError
+	panicking             bool              // Set after an error until the parser synchronizes at the next statement
+	loopDepth             int               // Number of loops around the current statement within the current function
+	scopes                []map[string]bool // Names declared in the blocks around the current statement
 	prefixParsingFunction map[token.Type]prefixParsingFunction
 	infixParsingFunction  map[token.Type]infixParsingFunction
 }
 
 func New(l lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []*ParseError{}, scopes: []map[string]bool{{}}}
 	p.registerPrefixFunctions()
 	p.registerInfixFunctions()
 	p.nextToken()
@@ -54,45 +98,63 @@ func New(l lexer.Lexer) *Parser {
 
 func (p *Parser) registerPrefixFunctions() {
 	p.prefixParsingFunction = map[token.Type]prefixParsingFunction{
-		token.IDENT:    p.parseIdentifier,
-		token.INT:      p.parseIntegerLiteral,
-		token.BANG:     p.parsePrefixExpression,
-		token.MINUS:    p.parsePrefixExpression,
-		token.TRUE:     p.parseBooleanExpressions,
-		token.FALSE:    p.parseBooleanExpressions,
-		token.LPAREN:   p.parseGroupedExpression,
-		token.IF:       p.parseIfExpression,
-		token.FUNCTION: p.parseFunctionLiteral,
-		token.STRING:   p.parseStringLiteral,
-		token.LBRACKET: p.parseArrayLiteral,
-		token.LBRACE:   p.parseHashLiteral,
+		token.IDENT:     p.parseIdentifier,
+		token.INT:       p.parseIntegerLiteral,
+		token.FLOAT:     p.parseFloatLiteral,
+		token.BIGINT:    p.parseBigIntLiteral,
+		token.BANG:      p.parsePrefixExpression,
+		token.MINUS:     p.parsePrefixExpression,
+		token.TILDE:     p.parsePrefixExpression,
+		token.TRUE:      p.parseBooleanExpressions,
+		token.FALSE:     p.parseBooleanExpressions,
+		token.LPAREN:    p.parseGroupedExpression,
+		token.IF:        p.parseIfExpression,
+		token.FUNCTION:  p.parseFunctionLiteral,
+		token.MACRO:     p.parseMacroLiteral,
+		token.STRING:    p.parseStringLiteral,
+		token.LBRACKET:  p.parseArrayLiteral,
+		token.LBRACE:    p.parseHashLiteral,
+		token.INCREMENT: p.parsePrefixUpdateExpression,
+		token.DECREMENT: p.parsePrefixUpdateExpression,
 	}
 }
 
 func (p *Parser) registerInfixFunctions() {
 	p.infixParsingFunction = map[token.Type]infixParsingFunction{
-		token.PLUS:     p.parseInfixExpression,
-		token.MINUS:    p.parseInfixExpression,
-		token.SLASH:    p.parseInfixExpression,
-		token.ASTARISK: p.parseInfixExpression,
-		token.EQ:       p.parseInfixExpression,
-		token.NEQ:      p.parseInfixExpression,
-		token.LT:       p.parseInfixExpression,
-		token.GT:       p.parseInfixExpression,
-		token.LPAREN:   p.parseCallExpression,
-		token.LBRACKET: p.parseIndexExpression,
+		token.PLUS:            p.parseInfixExpression,
+		token.MINUS:           p.parseInfixExpression,
+		token.SLASH:           p.parseInfixExpression,
+		token.ASTARISK:        p.parseInfixExpression,
+		token.EQ:              p.parseInfixExpression,
+		token.NEQ:             p.parseInfixExpression,
+		token.LT:              p.parseInfixExpression,
+		token.GT:              p.parseInfixExpression,
+		token.LE:              p.parseInfixExpression,
+		token.GE:              p.parseInfixExpression,
+		token.AND:             p.parseInfixExpression,
+		token.OR:              p.parseInfixExpression,
+		token.PERCENT:         p.parseInfixExpression,
+		token.POWER:           p.parseInfixExpression,
+		token.AMPERSAND:       p.parseInfixExpression,
+		token.PIPE:            p.parseInfixExpression,
+		token.CARET:           p.parseInfixExpression,
+		token.LSHIFT:          p.parseInfixExpression,
+		token.RSHIFT:          p.parseInfixExpression,
+		token.URSHIFT:         p.parseInfixExpression,
+		token.LPAREN:          p.parseCallExpression,
+		token.LBRACKET:        p.parseIndexExpression,
+		token.DOT:             p.parseMemberExpression,
+		token.ARROW:           p.parseArrowFunction,
+		token.ASSIGN:          p.parseAssignExpression,
+		token.PLUS_ASSIGN:     p.parseAssignExpression,
+		token.MINUS_ASSIGN:    p.parseAssignExpression,
+		token.ASTARISK_ASSIGN: p.parseAssignExpression,
+		token.SLASH_ASSIGN:    p.parseAssignExpression,
+		token.INCREMENT:       p.parsePostfixUpdateExpression,
+		token.DECREMENT:       p.parsePostfixUpdateExpression,
 	}
 }
 
-func (p *Parser) parseGroupedExpression() ast.Expression {
-	p.nextToken()
-	expression := p.parseExpression(constants.LOWEST)
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
-	return expression
-}
-
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -100,6 +162,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 	precedence := p.currentPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// Exponentiation is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 	return expression
@@ -138,15 +204,42 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	tok := p.curToken
 
 	val, err := strconv.ParseInt(tok.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(tok, nil, "integer %s does not fit into 64 bits, use %sn for a big integer", tok.Literal, tok.Literal)
+		return &ast.BadExpression{Token: tok}
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", tok.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		p.addError(tok, nil, "could not parse %q as integer", tok.Literal)
+		return &ast.BadExpression{Token: tok}
 	}
 
 	return &ast.IntegerLiteral{Token: tok, Value: val}
 }
 
+func (p *Parser) parseBigIntLiteral() ast.Expression {
+	tok := p.curToken
+
+	val, ok := new(big.Int).SetString(strings.TrimSuffix(tok.Literal, "n"), 10)
+	if !ok {
+		p.addError(tok, nil, "could not parse %q as big integer", tok.Literal)
+		return &ast.BadExpression{Token: tok}
+	}
+
+	return &ast.BigIntLiteral{Token: tok, Value: val}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	tok := p.curToken
+
+	val, err := strconv.ParseFloat(tok.Literal, 64)
+	if err != nil {
+		p.addError(tok, nil, "could not parse %q as float", tok.Literal)
+		return &ast.BadExpression{Token: tok}
+	}
+
+	return &ast.FloatLiteral{Token: tok, Value: val}
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -160,19 +253,71 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 	for p.curToken.Type != token.EOF {
-		statement := p.parseStatement()
-		program.Statements = append(program.Statements, statement)
+		// Empty statements are skipped
+		if !p.curTokenIs(token.SEMICOLON) {
+			program.Statements = append(program.Statements, p.parseStatementAndRecover())
+		}
 		p.nextToken()
 	}
 	return program
 }
 
+// Parses a statement and skips to the end of it when it had an error
+func (p *Parser) parseStatementAndRecover() ast.Statement {
+	statement := p.parseStatement()
+	if p.panicking {
+		p.synchronize()
+	}
+	return statement
+}
+
+// Skips tokens until the current token ends a statement or the next token starts one, Braces opened while
+// skipping are skipped along with their closing brace so that a broken block does not end the enclosing one.
+func (p *Parser) synchronize() {
+	p.panicking = false
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		}
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || statementKeywords[p.peekToken.Type] {
+				return
+			}
+		}
+		if p.peekTokenIs(token.EOF) {
+			return
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.FUNCTION:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionDeclaration()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -192,9 +337,8 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParsingFunction[p.curToken.Type]
 	if prefix == nil {
-		msg := fmt.Sprintf("no prefix parse function for %s found", p.curToken.Type)
-		p.errors = append(p.errors, msg)
-		return nil
+		p.addError(p.curToken, nil, "no prefix parse function for %s found", p.curToken.Type)
+		return &ast.BadExpression{Token: p.curToken}
 	}
 	expr := prefix()
 	for !p.curTokenIs(token.SEMICOLON) && precedence < p.peekPrecendence() {
@@ -214,27 +358,38 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	}
 	p.nextToken()
 	stmt.ReturnValue = p.parseExpression(constants.LOWEST)
-	for p.peekTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseLetStatement() ast.Statement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 	if !p.expectPeek(token.IDENT) {
-		return nil
+		return &ast.BadStatement{Token: stmt.Token}
 	}
 	stmt.Name = &ast.Identifier{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+	return p.parseLetValue(stmt)
+}
+
+// Parses the rest of a let statement once the name is known, The current token is the name
+func (p *Parser) parseLetValue(stmt *ast.LetStatement) ast.Statement {
 	if !p.expectPeek(token.ASSIGN) {
-		return nil
+		stmt.Value = &ast.BadExpression{Token: p.peekToken}
+		return stmt
 	}
 	p.nextToken()
 	stmt.Value = p.parseExpression(constants.LOWEST)
-	for p.peekTokenIs(token.SEMICOLON) {
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fn.Name = stmt.Name.Value
+	}
+	// The name is declared after the value, So the value still refers to the name of an enclosing block
+	p.declare(stmt.Name)
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -243,48 +398,93 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 func (p *Parser) parseIfExpression() ast.Expression {
 	expr := &ast.IfExpression{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return &ast.BadExpression{Token: expr.Token}
 	}
 	p.nextToken()
 	expr.Condition = p.parseExpression(constants.LOWEST)
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return &ast.BadExpression{Token: expr.Token}
 	}
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return &ast.BadExpression{Token: expr.Token}
 	}
 	expr.Consequence = p.parseBlockStatement()
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 		if !p.expectPeek(token.LBRACE) {
-			return nil
+			return &ast.BadExpression{Token: expr.Token}
 		}
 		expr.Alternative = p.parseBlockStatement()
 	}
 	return expr
 }
 
+// Every block has its own scope for the names declared in it
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	p.enterScope()
+	defer p.leaveScope()
+	return p.parseBlock()
+}
+
+func (p *Parser) parseBlock() *ast.BlockStatement {
 	block := &ast.BlockStatement{
 		Token:      p.curToken,
 		Statements: []ast.Statement{},
 	}
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
+		if !p.curTokenIs(token.SEMICOLON) {
+			block.Statements = append(block.Statements, p.parseStatementAndRecover())
+		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.EOF) {
+		p.addError(p.curToken, []token.Type{token.RBRACE}, "Expected next token is %s we got %s", token.RBRACE, token.EOF)
+	}
 	return block
 }
 
-func (p *Parser) Errors() []string {
+// Returns back the syntax errors in the order they were found
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
+// Returns back the parser errors as diagnostics, Used for rendering source excerpts
+func (p *Parser) Diagnostics() []*diagnostic.Diagnostic {
+	diagnostics := []*diagnostic.Diagnostic{}
+	for _, err := range p.errors {
+		diagnostics = append(diagnostics, err.Diagnostic())
+	}
+	return diagnostics
+}
+
+// Records an error found at the token, Errors are suppressed while the parser is panicking so that one
+// mistake is reported once instead of as a cascade of errors.
+func (p *Parser) addError(found token.Token, expected []token.Type, format string, a ...interface{}) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.errors = append(p.errors, &ParseError{
+		Pos:      found.Pos,
+		Message:  fmt.Sprintf(format, a...),
+		Expected: expected,
+		Found:    found,
+	})
+}
+
+// Records an error of a statement that was parsed completely, The parser does not need to recover from it so
+// it is reported even while panicking and does not skip any tokens.
+func (p *Parser) reportError(found token.Token, format string, a ...interface{}) {
+	p.errors = append(p.errors, &ParseError{
+		Pos:     found.Pos,
+		Message: fmt.Sprintf(format, a...),
+		Found:   found,
+	})
+}
+
 func (p *Parser) peekError(t token.Type) {
-	message := fmt.Sprintf("Expected next token is %s we got %s", t, p.peekToken.Type)
-	p.errors = append(p.errors, message)
+	p.addError(p.peekToken, []token.Type{t}, "Expected next token is %s we got %s", t, p.peekToken.Type)
 }
 
 func (p *Parser) peekTokenIs(t token.Type) bool {
@@ -304,31 +504,22 @@ func (p *Parser) expectPeek(t token.Type) bool {
 	return false
 }
 
-func (p *Parser) parseFunctionLiteral() ast.Expression {
-	lit := &ast.FunctionLiteral{Token: p.curToken}
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
-	lit.Parameters = p.parseFunctionParameters()
-	if !p.expectPeek(token.LBRACE) {
-		return nil
-	}
-	lit.Body = p.parseBlockStatement()
-	return lit
-}
-
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
 		return identifiers
 	}
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
@@ -341,6 +532,9 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return &ast.BadExpression{Token: exp.Token}
+	}
 	return exp
 }
 
@@ -351,6 +545,9 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return &ast.BadExpression{Token: array.Token}
+	}
 	return array
 }
 
@@ -378,8 +575,19 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	p.nextToken()
 	exp.Index = p.parseExpression(constants.LOWEST)
 	if !p.expectPeek(token.RBRACKET) {
-		return nil
+		return &ast.BadExpression{Token: exp.Token}
+	}
+	return exp
+}
+
+// Member access `left.name` is an index expression with the name as string key, So hashes are read and
+// assigned to with the same code as `left["name"]`
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	if !p.expectPeek(token.IDENT) {
+		return &ast.BadExpression{Token: exp.Token}
 	}
+	exp.Index = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 	return exp
 }
 
@@ -390,17 +598,17 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		key := p.parseExpression(constants.LOWEST)
 		if !p.expectPeek(token.COLON) {
-			return nil
+			return &ast.BadExpression{Token: hash.Token}
 		}
 		p.nextToken()
 		value := p.parseExpression(constants.LOWEST)
 		hash.Pairs[key] = value
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
-			return nil
+			return &ast.BadExpression{Token: hash.Token}
 		}
 	}
 	if !p.expectPeek(token.RBRACE) {
-		return nil
+		return &ast.BadExpression{Token: hash.Token}
 	}
 	return hash
 }
@@ -3,7 +3,9 @@ package parser
 import (
 	"compiler/ast"
 	"compiler/lexer"
+	"compiler/token"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -183,6 +185,22 @@ func TestOperatorPrecedingParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c",
+			"((a && b) || c)",
+		},
+		{
+			"a < b && c >= d == true",
+			"((a < b) && ((c >= d) == true))",
+		},
+		{
+			"1 + 2 <= 3 * 4",
+			"((1 + 2) <= (3 * 4))",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -205,6 +223,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"(5 + 5) * 2",
 			"((5 + 5) * 2)",
 		},
+		{"a % b * c", "((a % b) * c)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"a ** -b", "(a ** (-b))"},
+		{"a << b + c", "(a << (b + c))"},
+		{"a >> b < c >>> d", "((a >> b) < (c >>> d))"},
+		{"a & b == c", "(a & (b == c))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a && b | c", "(a && (b | c))"},
+		{"~a & ~b", "((~a) & (~b))"},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -233,6 +263,10 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 && 5;", 5, "&&", 5},
+		{"5 || 5;", 5, "||", 5},
 	}
 
 	for _, tt := range tests {
@@ -479,6 +513,46 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"obj.field", "(obj.field)"},
+		{"a.b.c", "((a.b).c)"},
+		{"arr.push(1, 2)", "(arr.push)(1, 2)"},
+		{"-obj.count * 2", "((-(obj.count)) * 2)"},
+		{"obj.items[0].name", "(((obj.items)[0]).name)"},
+		{"obj.count += 1", "((obj.count) += 1)"},
+		{"obj.count++", "((obj.count)++)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkforErrors(p, t)
+		if program.String() != tt.expected {
+			t.Errorf("expected %q got %q", tt.expected, program.String())
+		}
+	}
+	p := New(lexer.New("obj.field"))
+	program := p.ParseProgram()
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	member, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok || !member.IsMember() {
+		t.Fatalf("exp not member *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	key, ok := member.Index.(*ast.StringLiteral)
+	if !ok || key.Value != "field" {
+		t.Errorf("member index is not string literal field. got=%#v", member.Index)
+	}
+
+	p = New(lexer.New("obj.1"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for a dot without a name")
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 	l := lexer.New(input)
@@ -563,3 +637,450 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.14;"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 3.14 {
+		t.Errorf("literal.Value not %f. got=%f", 3.14, literal.Value)
+	}
+	if literal.TokenLiteral() != "3.14" {
+		t.Errorf("literal.TokenLiteral not %q. got=%q", "3.14", literal.TokenLiteral())
+	}
+}
+
+func TestBigIntLiteralExpression(t *testing.T) {
+	input := "123456789012345678901234567890n;"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	literal, ok := stmt.Expression.(*ast.BigIntLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.BigIntLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value.String() != "123456789012345678901234567890" {
+		t.Errorf("literal.Value not %s. got=%s", "123456789012345678901234567890", literal.Value)
+	}
+	if literal.TokenLiteral() != "123456789012345678901234567890n" {
+		t.Errorf("literal.TokenLiteral not %q. got=%q", "123456789012345678901234567890n", literal.TokenLiteral())
+	}
+}
+
+func TestIntegerLiteralOutOfRange(t *testing.T) {
+	p := New(lexer.New("9223372036854775808;"))
+	p.ParseProgram()
+	errors := p.Errors()
+	expected := "integer 9223372036854775808 does not fit into 64 bits, use 9223372036854775808n for a big integer"
+	if len(errors) != 1 || errors[0].Message != expected {
+		t.Fatalf("wrong errors. want=%q, got=%v", expected, errors)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedAST    []string
+	}{
+		{
+			"let = 5; let y = 10;",
+			[]string{"1:5: Expected next token is IDENT we got ="},
+			[]string{"<bad statement>", "let y = 10;"},
+		},
+		{
+			"let x 5; let y = 10; y",
+			[]string{"1:7: Expected next token is = we got INT"},
+			[]string{"let x = <bad expression>;", "let y = 10;", "y"},
+		},
+		{
+			"add(1, 2; let y = 3;",
+			[]string{"1:9: Expected next token is ) we got ;"},
+			[]string{"<bad expression>", "let y = 3;"},
+		},
+		{
+			"if (x { 1 } let y = 2;",
+			[]string{"1:7: Expected next token is ) we got {"},
+			[]string{"<bad expression>", "let y = 2;"},
+		},
+		{
+			"let f = fn(a, 1) { a }; let g = fn() { let = 1; 2 };",
+			[]string{"1:15: Expected next token is IDENT we got INT", "1:44: Expected next token is IDENT we got ="},
+			[]string{"let f = <bad expression>;", "let g = fn() <bad statement>2;"},
+		},
+		{
+			"let x = ; let y = ;",
+			[]string{"1:9: no prefix parse function for ; found", "1:19: no prefix parse function for ; found"},
+			[]string{"let x = <bad expression>;", "let y = <bad expression>;"},
+		},
+		{
+			"fn() { 1",
+			[]string{"1:9: Expected next token is } we got EOF"},
+			[]string{"fn() 1"},
+		},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		errors := []string{}
+		for _, err := range p.Errors() {
+			errors = append(errors, err.Error())
+		}
+		if strings.Join(errors, "\n") != strings.Join(tt.expectedErrors, "\n") {
+			t.Errorf("input %q: wrong errors.\nwant=%q\ngot =%q", tt.input, tt.expectedErrors, errors)
+		}
+		statements := []string{}
+		for _, s := range program.Statements {
+			if s == nil {
+				t.Fatalf("input %q: program has a nil statement", tt.input)
+			}
+			statements = append(statements, s.String())
+		}
+		if strings.Join(statements, "\n") != strings.Join(tt.expectedAST, "\n") {
+			t.Errorf("input %q: wrong statements.\nwant=%q\ngot =%q", tt.input, tt.expectedAST, statements)
+		}
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	p := New(lexer.New("let x 5;"))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. got=%d", len(errors))
+	}
+	err := errors[0]
+	if err.Pos.Line != 1 || err.Pos.Column != 7 {
+		t.Errorf("wrong position. got=%s", err.Pos)
+	}
+	if len(err.Expected) != 1 || err.Expected[0] != token.ASSIGN {
+		t.Errorf("wrong expected tokens. got=%v", err.Expected)
+	}
+	if err.Found.Type != token.INT || err.Found.Literal != "5" {
+		t.Errorf("wrong found token. got=%+v", err.Found)
+	}
+}
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x }", "while(x < 10) x"},
+		{"for (let i = 0; i < 10; i++) { i }", "for (let i = 0; (i < 10); (i++)) i"},
+		{"for (const x of xs) { x }", "for (const x of xs) x"},
+		{"for (;;) { break; }", "for (; ; ) break;"},
+		{"for (i; i; i) { continue; }", "for (i; i; i) continue;"},
+		{"for (let x of [1, 2]) { x }", "for (x of [1, 2]) x"},
+		{"for (k in h) { k }", "for (k in h) k"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkforErrors(p, t)
+		if len(program.Statements) != 1 {
+			t.Fatalf("input %q: program does not contain 1 statement got %d", tt.input, len(program.Statements))
+		}
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("input %q: wrong statement. want=%q got=%q", tt.input, tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestForEachStatementParsing(t *testing.T) {
+	p := New(lexer.New("for (let x of items) { x; }"))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+	stmt, ok := program.Statements[0].(*ast.ForEachStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForEachStatement got %T", program.Statements[0])
+	}
+	if stmt.Variable.Value != "x" || stmt.Operator != "of" {
+		t.Errorf("wrong loop variable or operator. got=%s %s", stmt.Variable.Value, stmt.Operator)
+	}
+	testIdentifier(t, stmt.Iterable, "items")
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("stmt.Body has not 1 statement got %d", len(stmt.Body.Statements))
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break is only allowed inside a loop"},
+		{"if (true) { continue; }", "1:13: continue is only allowed inside a loop"},
+		{"while (true) { let f = fn() { break; }; }", "1:31: break is only allowed inside a loop"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: wrong number of errors. got=%d", tt.input, len(errors))
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestAssignmentParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "(x = 5)"},
+		{"x = y = 1 + 2", "(x = (y = (1 + 2)))"},
+		{"x += a * b", "(x += (a * b))"},
+		{"a[1] -= 2", "((a[1]) -= 2)"},
+		{`h["k"] /= 2 || 3`, "((h[k]) /= (2 || 3))"},
+		{"x++", "(x++)"},
+		{"--a[0]", "(--(a[0]))"},
+		{"-x++", "(-(x++))"},
+		{"x++ + ++y", "((x++) + (++y))"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkforErrors(p, t)
+		if program.String() != tt.expected {
+			t.Errorf("input %q: want=%q got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2", "1:3: invalid assignment target 1"},
+		{"f() += 1", "1:5: invalid assignment target f()"},
+		{"(a + b)++", "1:8: invalid assignment target (a + b)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: wrong number of errors. got=%d", tt.input, len(errors))
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestRedeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; let x = 2;", []string{"1:16: cannot redeclare x"}},
+		{"let x = 1; const x = 2; x", []string{"1:18: cannot redeclare x"}},
+		{"let f = fn(a) { let a = 1; };", []string{"1:21: cannot redeclare a"}},
+		{"let f = fn(a, a) { a };", []string{"1:15: cannot redeclare a"}},
+		{"for (let x of xs) { }; let x = 1; let x = 2;", []string{"1:39: cannot redeclare x"}},
+		{"let x = 1; if (true) { let x = 2; } else { let x = 3; }", []string{}},
+		{"let i = 0; for (let i = 0; i < 1; i++) { let i = 5; }", []string{}},
+		{"let x = 1; let f = fn(x) { x };", []string{}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := []string{}
+		for _, err := range p.Errors() {
+			errors = append(errors, err.Error())
+		}
+		if strings.Join(errors, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("input %q: wrong errors.\nwant=%q\ngot =%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	p := New(lexer.New("const answer = 42;"))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.LetStatement got %T", program.Statements[0])
+	}
+	if !stmt.IsConst() || stmt.String() != "const answer = 42;" {
+		t.Errorf("wrong const statement. got=%q", stmt.String())
+	}
+}
+
+func TestFunctionDeclarationParsing(t *testing.T) {
+	p := New(lexer.New("function add(a, b) { a + b }"))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+	decl, ok := program.Statements[0].(*ast.FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionDeclaration got %T", program.Statements[0])
+	}
+	if decl.Name.Value != "add" || decl.Function.Name != "add" {
+		t.Errorf("wrong function name. got=%q and %q", decl.Name.Value, decl.Function.Name)
+	}
+	if len(decl.Function.Parameters) != 2 {
+		t.Fatalf("function parameters wrong, got back %d", len(decl.Function.Parameters))
+	}
+	if decl.String() != "function add(a, b) (a + b)" {
+		t.Errorf("wrong string. got=%q", decl.String())
+	}
+}
+
+func TestFunctionSyntaxParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = function(x) { x };", "let f = function(x) x;"},
+		{"let f = (a, b) => a + b;", "let f = (a, b) => (a + b);"},
+		{"let f = x => x * 2;", "let f = (x) => (x * 2);"},
+		{"let f = (x) => { x };", "let f = (x) => x;"},
+		{"let f = () => 1;", "let f = () => 1;"},
+		{"map(xs, x => x + 1, 2)", "map(xs, (x) => (x + 1), 2)"},
+		{"x => y => x + y", "(x) => (y) => (x + y)"},
+		{"a = () => b = 1", "(a = () => (b = 1))"},
+		{"(1 + 2) * 3", "((1 + 2) * 3)"},
+		{"fn(x) { x }(1)", "fn(x) x(1)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkforErrors(p, t)
+		if program.String() != tt.expected {
+			t.Errorf("expected %q got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestFunctionSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(a, 1) => a", "1:8: invalid arrow function parameter 1"},
+		{"a + b => 1", "1:7: invalid arrow function parameter (a + b)"},
+		{"(a, b)", "1:7: Expected next token is => we got EOF"},
+		{"function f() { 1 }; function f() { 2 }", "1:30: cannot redeclare f"},
+		{"function f(a) { let a = 1; }", "1:21: cannot redeclare a"},
+		{"let g = (a) => { let a = 1; }", "1:22: cannot redeclare a"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected an error", tt.input)
+			continue
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	p := New(lexer.New(`macro(x, y) { x + y; }`))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement got %T", program.Statements[0])
+	}
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral got %T", stmt.Expression)
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong, got back %d", len(macro.Parameters))
+	}
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+	if macro.String() != "macro(x, y) (x + y)" {
+		t.Errorf("wrong string. got=%q", macro.String())
+	}
+}
+
+func TestTryStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a() } catch (e) { b(e) }", "try {a()} catch (e) {b(e)}"},
+		{"try { a() } finally { b() }", "try {a()} finally {b()}"},
+		{"try { a() } catch { b() } finally { c() }", "try {a()} catch {b()} finally {c()}"},
+		{"throw error(\"bad\");", "throw error(bad);"},
+		{"try { throw 1 } catch (e) { throw e }", "try {throw 1;} catch (e) {throw e;}"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkforErrors(p, t)
+		if program.String() != tt.expected {
+			t.Errorf("expected %q got %q", tt.expected, program.String())
+		}
+	}
+	p := New(lexer.New("try { 1 } catch (err) { 2 }"))
+	program := p.ParseProgram()
+	checkforErrors(p, t)
+	stmt, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.TryStatement got %T", program.Statements[0])
+	}
+	testLiteralExpression(t, stmt.Param, "err")
+	if stmt.Finally != nil {
+		t.Errorf("finally block should be nil. got=%q", stmt.Finally.String())
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { 1 }", "1:10: Expected next token is CATCH or FINALLY we got EOF"},
+		{"try { 1 } catch (1) { }", "1:18: Expected next token is IDENT we got INT"},
+		{"try { 1 } catch (e) { let e = 2; }", "1:27: cannot redeclare e"},
+		{"throw;", "1:6: no prefix parse function for ; found"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected an error", tt.input)
+			continue
+		}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("input %q: wrong error. want=%q, got=%q", tt.input, tt.expected, errors[0].Error())
+		}
+	}
+}
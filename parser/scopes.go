@@ -0,0 +1,23 @@
+package parser
+
+import "compiler/ast"
+
+// Names can be declared once per block, Blocks inside of it may declare the same name again and shadow it
+
+func (p *Parser) enterScope() {
+	p.scopes = append(p.scopes, map[string]bool{})
+}
+
+func (p *Parser) leaveScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// Declares the name in the innermost block and reports it when the block has already declared it
+func (p *Parser) declare(name *ast.Identifier) {
+	scope := p.scopes[len(p.scopes)-1]
+	if scope[name.Value] {
+		p.reportError(name.Token, "cannot redeclare %s", name.Value)
+		return
+	}
+	scope[name.Value] = true
+}
@@ -0,0 +1,476 @@
+package relp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Returned back by ReadLine when the user pressed Ctrl-C, The current input is thrown away
+var errInterrupted = errors.New("interrupted")
+
+// Reads the input of the REPL one line at a time
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// Reads plain lines, Used when the input is not a terminal so that piping a script into the REPL still works
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func newScannerReader(in io.Reader, out io.Writer) *scannerReader {
+	return &scannerReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+func (s *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.scanner.Text(), nil
+}
+
+// Key codes of the control keys the editor handles
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Keys which are sent as escape sequences, They are given values outside of the range of runes
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDeleteForward
+	keyUnknown
+)
+
+// Line editor for terminals in raw mode, It supports moving the cursor, history with reverse search and tab
+// completion. The terminal has to be switched to raw mode by the caller.
+type editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *history
+	complete func(prefix string) []string
+
+	prompt     string
+	buffer     []rune
+	cursor     int
+	historyPos int    // Position in the history while browsing it, len(entries) is the line being edited
+	pending    string // Line that was being edited before browsing the history
+	lastKey    rune
+}
+
+func newEditor(in io.Reader, out io.Writer, h *history, complete func(prefix string) []string) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, history: h, complete: complete}
+}
+
+// Reads a line with editing, The line is added to the history once it is entered
+func (e *editor) ReadLine(prompt string) (string, error) {
+	e.prompt = prompt
+	e.buffer = e.buffer[:0]
+	e.cursor = 0
+	e.historyPos = len(e.history.entries)
+	e.pending = ""
+	e.refresh()
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case keyEnter, '\n':
+			fmt.Fprint(e.out, "\r\n")
+			line := string(e.buffer)
+			e.history.Add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.buffer) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteForward()
+		case keyTab:
+			e.completeWord()
+		case keyCtrlR:
+			line, done, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if done {
+				fmt.Fprint(e.out, "\r\n")
+				e.history.Add(line)
+				return line, nil
+			}
+		default:
+			e.edit(key)
+		}
+		e.lastKey = key
+		e.refresh()
+	}
+}
+
+// Applies a key that changes the line or moves the cursor
+func (e *editor) edit(key rune) {
+	switch key {
+	case keyBackspace, keyDelete:
+		if e.cursor > 0 {
+			e.buffer = append(e.buffer[:e.cursor-1], e.buffer[e.cursor:]...)
+			e.cursor--
+		}
+	case keyDeleteForward:
+		e.deleteForward()
+	case keyLeft, keyCtrlB:
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case keyRight, keyCtrlF:
+		if e.cursor < len(e.buffer) {
+			e.cursor++
+		}
+	case keyHome, keyCtrlA:
+		e.cursor = 0
+	case keyEnd, keyCtrlE:
+		e.cursor = len(e.buffer)
+	case keyCtrlK:
+		e.buffer = e.buffer[:e.cursor]
+	case keyCtrlU:
+		e.buffer = append(e.buffer[:0], e.buffer[e.cursor:]...)
+		e.cursor = 0
+	case keyCtrlW:
+		start := e.cursor
+		for start > 0 && e.buffer[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.buffer[start-1] != ' ' {
+			start--
+		}
+		e.buffer = append(e.buffer[:start], e.buffer[e.cursor:]...)
+		e.cursor = start
+	case keyCtrlL:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+	case keyUp, keyCtrlP:
+		e.browseHistory(-1)
+	case keyDown, keyCtrlN:
+		e.browseHistory(1)
+	default:
+		if key >= ' ' {
+			e.insert([]rune{key})
+		}
+	}
+}
+
+func (e *editor) insert(runes []rune) {
+	buffer := make([]rune, 0, len(e.buffer)+len(runes))
+	buffer = append(buffer, e.buffer[:e.cursor]...)
+	buffer = append(buffer, runes...)
+	buffer = append(buffer, e.buffer[e.cursor:]...)
+	e.buffer = buffer
+	e.cursor += len(runes)
+}
+
+func (e *editor) deleteForward() {
+	if e.cursor < len(e.buffer) {
+		e.buffer = append(e.buffer[:e.cursor], e.buffer[e.cursor+1:]...)
+	}
+}
+
+// Moves through the history, The line that was being edited is kept and comes back after the newest entry
+func (e *editor) browseHistory(direction int) {
+	pos := e.historyPos + direction
+	if pos < 0 || pos > len(e.history.entries) {
+		return
+	}
+	if e.historyPos == len(e.history.entries) {
+		e.pending = string(e.buffer)
+	}
+	e.historyPos = pos
+	if pos == len(e.history.entries) {
+		e.buffer = []rune(e.pending)
+	} else {
+		e.buffer = []rune(e.history.entries[pos])
+	}
+	e.cursor = len(e.buffer)
+}
+
+// Completes the word before the cursor, A single candidate is inserted right away. With several candidates
+// their common prefix is inserted and pressing tab a second time lists them.
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	start := e.cursor
+	for start > 0 && isIdentifierRune(e.buffer[start-1]) {
+		start--
+	}
+	prefix := string(e.buffer[start:e.cursor])
+	if prefix == "" {
+		return
+	}
+	candidates := e.complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) {
+		e.insert([]rune(common[len(prefix):]))
+		return
+	}
+	if len(candidates) > 1 && e.lastKey == keyTab {
+		fmt.Fprint(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+// Searches the history backwards for entries containing the typed text, Ctrl-R moves to the next older
+// match, Enter runs the match, Ctrl-G or Ctrl-C gives up and any other key keeps the match for editing.
+func (e *editor) reverseSearch() (string, bool, error) {
+	original := string(e.buffer)
+	query := []rune{}
+	match := ""
+	from := len(e.history.entries) - 1
+	for {
+		found := false
+		for i := from; i >= 0 && len(query) > 0; i-- {
+			if strings.Contains(e.history.entries[i], string(query)) {
+				match = e.history.entries[i]
+				from = i
+				found = true
+				break
+			}
+		}
+		if len(query) == 0 {
+			match = ""
+		}
+		status := "reverse-i-search"
+		if len(query) > 0 && !found {
+			status = "failing reverse-i-search"
+		}
+		fmt.Fprintf(e.out, "\r\x1b[K(%s)`%s': %s", status, string(query), match)
+		key, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case keyEnter, '\n':
+			return match, true, nil
+		case keyCtrlG, keyCtrlC:
+			e.buffer = []rune(original)
+			e.cursor = len(e.buffer)
+			return "", false, nil
+		case keyCtrlR:
+			from--
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			from = len(e.history.entries) - 1
+		default:
+			if key >= ' ' {
+				query = append(query, key)
+				continue
+			}
+			if match != "" {
+				e.buffer = []rune(match)
+			}
+			e.cursor = len(e.buffer)
+			return "", false, nil
+		}
+	}
+}
+
+// Redraws the line and puts the cursor back in place
+func (e *editor) refresh() {
+	fmt.Fprintf(e.out, "\r\x1b[K%s%s", e.prompt, string(e.buffer))
+	if back := len(e.buffer) - e.cursor; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// Reads a key, Escape sequences of the arrow and navigation keys are turned into a single key
+func (e *editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r != keyEscape {
+		return r, nil
+	}
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	code, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if code < '0' || code > '9' {
+		return keyUnknown, nil
+	}
+	// Sequences like ESC [ 3 ~ end with a tilde
+	number := []rune{code}
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if r == '~' {
+			break
+		}
+		number = append(number, r)
+	}
+	switch string(number) {
+	case "1", "7":
+		return keyHome, nil
+	case "4", "8":
+		return keyEnd, nil
+	case "3":
+		return keyDeleteForward, nil
+	}
+	return keyUnknown, nil
+}
+
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// Upper limit on the number of lines kept in the history
+const maxHistory = 1000
+
+// History of the entered lines, Every line is appended to the history file right away so that it is kept
+// even when the REPL does not exit cleanly.
+type history struct {
+	entries []string
+	path    string
+}
+
+// Returns back the path of the history file in the home directory of the user
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bjs_history")
+}
+
+// Loads the history from the file, A missing file gives an empty history. The file is rewritten when it has
+// grown past the limit.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+	}
+	return h
+}
+
+// Adds the line to the history, Blank lines and repeats of the previous line are skipped
+func (h *history) Add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// Returns back the sorted and unique words starting with the prefix
+func completions(prefix string, sources ...[]string) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, words := range sources {
+		for _, word := range words {
+			if strings.HasPrefix(word, prefix) && !seen[word] {
+				seen[word] = true
+				candidates = append(candidates, word)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
@@ -0,0 +1,190 @@
+package relp
+
+import (
+	"compiler/compiler"
+	"compiler/lexer"
+	"compiler/object"
+	"compiler/token"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Meta-commands control the REPL session itself, They are typed at the prompt starting with a colon.
+type metaCommand struct {
+	name    string
+	usage   string
+	summary string
+	run     func(r *repl, arg string)
+}
+
+var metaCommands []*metaCommand
+
+func init() {
+	metaCommands = []*metaCommand{
+		{"load", ":load file.bjs", "Run a script in the current session", loadMetaCommand},
+		{"reset", ":reset", "Forget every binding of the session", resetMetaCommand},
+		{"env", ":env", "List the bindings of the session", envMetaCommand},
+		{"tokens", ":tokens [source]", "Print the tokens of the source or of the last input", tokensMetaCommand},
+		{"ast", ":ast [source]", "Print the parsed source or the last input", astMetaCommand},
+		{"bytecode", ":bytecode [source]", "Print the bytecode of the source or of the last input", bytecodeMetaCommand},
+		{"engine", ":engine [eval|vm]", "Show or switch the engine that runs the input", engineMetaCommand},
+		{"quit", ":quit", "Leave the REPL", quitMetaCommand},
+		{"help", ":help", "List the meta-commands", helpMetaCommand},
+	}
+}
+
+// Runs the meta-command typed at the prompt, The line starts with the colon
+func (r *repl) runMetaCommand(line string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.TrimSpace(arg)
+	for _, cmd := range metaCommands {
+		if cmd.name == name || (name == "q" && cmd.name == "quit") {
+			cmd.run(r, arg)
+			return
+		}
+	}
+	fmt.Fprintf(r.out, "unknown command :%s, type :help for the list of commands\n", name)
+}
+
+// Returns back the source given to a meta-command, The last input is used when no source is given
+func (r *repl) sourceArgument(arg string) (string, bool) {
+	if arg != "" {
+		return arg, true
+	}
+	if r.last == "" {
+		fmt.Fprintln(r.out, "nothing to show, give the source after the command")
+		return "", false
+	}
+	return r.last, true
+}
+
+func loadMetaCommand(r *repl, arg string) {
+	if arg == "" {
+		fmt.Fprintln(r.out, "usage: :load file.bjs")
+		return
+	}
+	source, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintf(r.out, "could not load %s: %s\n", arg, err)
+		return
+	}
+	r.run(string(source))
+}
+
+func resetMetaCommand(r *repl, arg string) {
+	r.reset()
+	fmt.Fprintln(r.out, "session reset")
+}
+
+func envMetaCommand(r *repl, arg string) {
+	if r.compilationMode {
+		r.printGlobals()
+		return
+	}
+	names := r.env.Names()
+	if len(names) == 0 {
+		fmt.Fprintln(r.out, "no bindings")
+		return
+	}
+	for _, name := range names {
+		value, _ := r.env.Get(name)
+		fmt.Fprintf(r.out, "%s = %s\n", name, value.Inspect())
+	}
+}
+
+// Lists the global bindings of the virtual machine along with their values
+func (r *repl) printGlobals() {
+	found := false
+	for _, symbol := range r.symbolTable.Symbols() {
+		if !r.bound(symbol) {
+			continue
+		}
+		fmt.Fprintf(r.out, "%s = %s\n", symbol.Name, r.globals[symbol.Index].Inspect())
+		found = true
+	}
+	if !found {
+		fmt.Fprintln(r.out, "no bindings")
+	}
+}
+
+func tokensMetaCommand(r *repl, arg string) {
+	source, ok := r.sourceArgument(arg)
+	if !ok {
+		return
+	}
+	l := lexer.New(source)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		fmt.Fprintf(r.out, "%-8s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+}
+
+func astMetaCommand(r *repl, arg string) {
+	source, ok := r.sourceArgument(arg)
+	if !ok {
+		return
+	}
+	program, ok := r.parse("", source)
+	if !ok {
+		return
+	}
+	for _, statement := range program.Statements {
+		fmt.Fprintln(r.out, statement.String())
+	}
+}
+
+func bytecodeMetaCommand(r *repl, arg string) {
+	source, ok := r.sourceArgument(arg)
+	if !ok {
+		return
+	}
+	program, ok := r.parse("", source)
+	if !ok {
+		return
+	}
+	// The bindings of the session are visible, but the constants of earlier inputs are left out
+	comp := compiler.NewWithState(r.symbolTable.Copy(), []object.Object{})
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(r.out, "Woops! Compilation failed:\n %s\n", err)
+		return
+	}
+	bytecode := comp.ByteCode()
+	fmt.Fprintln(r.out, "Constants:")
+	for i, constant := range bytecode.Constants {
+		fmt.Fprintf(r.out, "%04d %s %s\n", i, constant.Type(), constant.Inspect())
+	}
+	fmt.Fprintln(r.out, "Instructions:")
+	fmt.Fprint(r.out, bytecode.Instructions.String())
+}
+
+func engineMetaCommand(r *repl, arg string) {
+	switch arg {
+	case "":
+	case "eval":
+		r.compilationMode = false
+	case "vm":
+		r.compilationMode = true
+	default:
+		fmt.Fprintf(r.out, "unknown engine %q, use eval or vm\n", arg)
+		return
+	}
+	if r.compilationMode {
+		fmt.Fprintln(r.out, "engine: vm")
+	} else {
+		fmt.Fprintln(r.out, "engine: eval")
+	}
+}
+
+func quitMetaCommand(r *repl, arg string) {
+	r.quit = true
+}
+
+func helpMetaCommand(r *repl, arg string) {
+	for _, cmd := range metaCommands {
+		fmt.Fprintf(r.out, "  %-20s %s\n", cmd.usage, cmd.summary)
+	}
+}
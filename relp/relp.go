@@ -1,64 +1,283 @@
+// This package has the read eval print loop for BJS, Input is read until it forms a complete program so that
+// functions and blocks can span several lines. Lines starting with a colon are meta-commands of the REPL.
 package relp
 
 import (
-	"bufio"
+	"compiler/ast"
 	"compiler/compiler"
 	"compiler/constants"
+	"compiler/diagnostic"
 	"compiler/evaluator"
 	"compiler/lexer"
 	"compiler/object"
 	"compiler/parser"
+	"compiler/token"
 	"compiler/virtualmachine"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 )
 
+// State of a REPL session, The enviornment keeps the bindings of the interpreter between inputs and the
+// symbol table, constants and globals keep them for the compiler and the virtual machine. Every engine has its
+// own bindings.
+type repl struct {
+	out             io.Writer
+	compilationMode bool
+	env             *object.Enviornment
+	macros          *object.Enviornment // Macros are expanded before either engine runs the input
+	symbolTable     *compiler.SymbolTable
+	constants       []object.Object
+	globals         []object.Object
+	last            string // The last input that was run, Used by meta-commands when no source is given
+	// Sources of the inputs that were run by the names their positions have, Errors of functions from an
+	// earlier input are shown with the source of that input
+	sources map[string]string
+	quit    bool
+}
+
+func newRepl(out io.Writer, compilationMode bool) *repl {
+	r := &repl{out: out, compilationMode: compilationMode}
+	r.reset()
+	return r
+}
+
+// Starts over with an empty session
+func (r *repl) reset() {
+	r.env = object.NewEnviornment()
+	r.macros = object.NewEnviornment()
+	r.symbolTable = compiler.NewSymbolTableWithBuiltins()
+	r.constants = []object.Object{}
+	r.globals = make([]object.Object, virtualmachine.GlobalsSize)
+	r.last = ""
+	r.sources = map[string]string{}
+}
+
+// Starts the REPL, On a terminal the input is read with a line editor which has history and tab completion.
+// Any other input, such as a pipe, is read line by line.
 func StartRELP(input io.Reader, out io.Writer, compilationMode bool) {
-	scanner := bufio.NewScanner(input)
-	env := object.NewEnviornment()
-	for {
-		fmt.Printf(constants.PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+	r := newRepl(out, compilationMode)
+	reader := r.newLineReader(input, out)
+	var buffer strings.Builder
+	for !r.quit {
+		prompt := constants.PROMPT
+		if buffer.Len() != 0 {
+			prompt = constants.CONTINUATION_PROMPT
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		line, err := reader.ReadLine(prompt)
+		if err == errInterrupted {
+			buffer.Reset()
 			continue
 		}
-		if compilationMode {
-			comp := compiler.New()
-			err := comp.Compile(program)
-			if err != nil {
-				fmt.Fprintf(out, "Woops! Compilation failed:\n %s\n", err)
-				continue
+		if err != nil {
+			if buffer.Len() != 0 {
+				r.run(buffer.String())
 			}
-			machine := virtualmachine.New(comp.ByteCode())
-			err = machine.Run()
-			if err != nil {
-				fmt.Fprintf(out, "Woops! Bytecode Execution failed:\n %s\n", err)
-				continue
-			}
-			stackTop := machine.StackTop()
-			io.WriteString(out, stackTop.Inspect())
-			io.WriteString(out, "\n")
-		} else {
-			evaluated := evaluator.Eval(program, env)
-			if evaluated != nil {
-				io.WriteString(out, evaluated.Inspect())
-				io.WriteString(out, "\n")
+			return
+		}
+		if buffer.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			r.runMetaCommand(strings.TrimSpace(line))
+			continue
+		}
+		buffer.WriteString(line)
+		buffer.WriteString("\n")
+		// An empty line ends the input even when it is incomplete, so a mistake can not trap the user
+		if strings.TrimSpace(line) != "" && IsIncomplete(buffer.String()) {
+			continue
+		}
+		if strings.TrimSpace(buffer.String()) != "" {
+			r.run(buffer.String())
+		}
+		buffer.Reset()
+	}
+}
+
+// Returns back the line editor when both input and output are a terminal, Otherwise lines are scanned
+func (r *repl) newLineReader(input io.Reader, out io.Writer) lineReader {
+	in, ok := input.(*os.File)
+	if !ok || !isTerminal(int(in.Fd())) {
+		return newScannerReader(input, out)
+	}
+	if f, ok := out.(*os.File); !ok || !isTerminal(int(f.Fd())) {
+		return newScannerReader(input, out)
+	}
+	e := newEditor(in, out, loadHistory(historyPath()), r.completions)
+	return &terminalReader{fd: int(in.Fd()), editor: e, fallback: newScannerReader(input, out)}
+}
+
+// Candidates for tab completion, Keywords, builtins and the names bound in the session
+func (r *repl) completions(prefix string) []string {
+	builtins := []string{}
+	for _, def := range object.Builtins {
+		builtins = append(builtins, def.Name)
+	}
+	globals := []string{}
+	for _, symbol := range r.symbolTable.Symbols() {
+		if r.bound(symbol) {
+			globals = append(globals, symbol.Name)
+		}
+	}
+	return completions(prefix, token.Keywords(), builtins, r.env.Names(), globals)
+}
+
+// Switches the terminal to raw mode while the editor reads a line, Lines are scanned when that is not possible
+type terminalReader struct {
+	fd       int
+	editor   *editor
+	fallback lineReader
+}
+
+func (t *terminalReader) ReadLine(prompt string) (string, error) {
+	state, err := makeRaw(t.fd)
+	if err != nil {
+		return t.fallback.ReadLine(prompt)
+	}
+	defer restoreTerminal(t.fd, state)
+	return t.editor.ReadLine(prompt)
+}
+
+// Parses and runs the source with the engine of the session and prints back the result, Every input is named
+// after its number so positions tell which input they belong to
+func (r *repl) run(source string) {
+	name := fmt.Sprintf("<repl %d>", len(r.sources)+1)
+	r.sources[name] = source
+	program, ok := r.parse(name, source)
+	if !ok {
+		return
+	}
+	r.last = source
+	if r.compilationMode {
+		// An input that does not compile leaves no new bindings behind, Its definitions are rolled back
+		snapshot := r.symbolTable.Copy()
+		comp := compiler.NewWithState(r.symbolTable, r.constants)
+		err := comp.Compile(program)
+		if err != nil {
+			r.symbolTable = snapshot
+			fmt.Fprintf(r.out, "Woops! Compilation failed:\n %s\n", err)
+			return
+		}
+		bytecode := comp.ByteCode()
+		r.constants = bytecode.Constants
+		machine := virtualmachine.NewWithGlobalsStore(bytecode, r.globals)
+		err = machine.Run()
+		if err != nil {
+			// Bindings made before the error are kept like in the interpreter
+			r.uninitializeGlobals()
+			message := err.Error()
+			if runtimeErr, ok := err.(*virtualmachine.RuntimeError); ok {
+				message = r.render(runtimeErr.Diagnostic())
 			}
+			fmt.Fprintf(r.out, "Woops! Bytecode Execution failed:\n %s\n", message)
+			return
+		}
+		// Let statements and loops have no value, Same as in the interpreter
+		if !program.EndsWithValue() {
+			return
+		}
+		stackTop := machine.LastPoppedStackElem()
+		if stackTop != nil {
+			io.WriteString(r.out, stackTop.Inspect())
+			io.WriteString(r.out, "\n")
+		}
+		return
+	}
+	evaluated := evaluator.Eval(program, r.env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		io.WriteString(r.out, "ERROR: "+r.render(errObj.Diagnostic())+"\n")
+		return
+	}
+	if evaluated != nil {
+		io.WriteString(r.out, evaluated.Inspect())
+		io.WriteString(r.out, "\n")
+	}
+}
+
+// Renders the diagnostic with the source of the input its position belongs to
+func (r *repl) render(d *diagnostic.Diagnostic) string {
+	return d.Render(r.sources[d.Pos.File])
+}
+
+// Names declared by an input which failed before their let statement ran have no value, They are left
+// uninitialized so that reading them raises a ReferenceError like in the interpreter
+func (r *repl) uninitializeGlobals() {
+	for _, symbol := range r.symbolTable.Symbols() {
+		if symbol.Scope == compiler.GlobalScope && r.globals[symbol.Index] == nil {
+			r.globals[symbol.Index] = &object.Uninitialized{Name: symbol.Name}
 		}
+	}
+}
+
+// Reports whether the global of the symbol has a value
+func (r *repl) bound(symbol compiler.Symbol) bool {
+	if symbol.Scope != compiler.GlobalScope || r.globals[symbol.Index] == nil {
+		return false
+	}
+	_, uninitialized := r.globals[symbol.Index].(*object.Uninitialized)
+	return !uninitialized
+}
+
+// Parses the source and expands its macros, Macros defined by the input stay defined for later inputs.
+// Parser and expansion errors are printed back. The positions of the program get the name of the input.
+func (r *repl) parse(name, source string) (*ast.Program, bool) {
+	p := parser.New(lexer.NewWithFile(name, source))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		printParserErrors(r.out, source, p.Diagnostics())
+		return nil, false
+	}
+	evaluator.DefineMacros(program, r.macros)
+	program, err := evaluator.ExpandMacros(program, r.macros)
+	if err != nil {
+		printParserErrors(r.out, source, []*diagnostic.Diagnostic{err.(*diagnostic.Diagnostic)})
+		return nil, false
+	}
+	return program, true
+}
+
+// Tokens after which an input can not end, A line ending with one of them is continued on the next line
+var continuationTokens = map[token.Type]bool{
+	token.ASSIGN: true, token.PLUS: true, token.MINUS: true, token.ASTARISK: true, token.SLASH: true,
+	token.LT: true, token.GT: true, token.LE: true, token.GE: true, token.EQ: true, token.NEQ: true,
+	token.AND: true, token.OR: true, token.BANG: true, token.COMMA: true, token.COLON: true,
+	token.PLUS_ASSIGN: true, token.MINUS_ASSIGN: true, token.ASTARISK_ASSIGN: true, token.SLASH_ASSIGN: true,
+	token.PERCENT: true, token.POWER: true, token.AMPERSAND: true, token.PIPE: true, token.CARET: true,
+	token.TILDE: true, token.LSHIFT: true, token.RSHIFT: true, token.URSHIFT: true,
+}
 
+// Reports whether the source needs more lines to be complete, That is when brackets are left open, a string is
+// not terminated or the source ends with an operator.
+func IsIncomplete(source string) bool {
+	l := lexer.New(source)
+	depth := 0
+	var last token.Token
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		case token.STRING:
+			// The opening quote is at the position of the token, An unterminated string runs until the end
+			closing := tok.Pos.Offset + len(tok.Literal) + 1
+			if closing >= len(source) || source[closing] != '"' {
+				return true
+			}
+		}
+		last = tok
+	}
+	if depth > 0 {
+		return true
 	}
+	return continuationTokens[last.Type]
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+func printParserErrors(out io.Writer, source string, errors []*diagnostic.Diagnostic) {
+	for _, d := range errors {
+		io.WriteString(out, d.Render(source)+"\n")
 	}
 }
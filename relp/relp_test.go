@@ -0,0 +1,241 @@
+package relp
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runSession(input string, compilationMode bool) string {
+	var out bytes.Buffer
+	StartRELP(strings.NewReader(input), &out, compilationMode)
+	return out.String()
+}
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 + 2", false},
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) {\n a + b\n}", false},
+		{"[1, 2,", true},
+		{"add(1,", true},
+		{"let x = ", true},
+		{"1 +", true},
+		{"true &&", true},
+		{`"unterminated`, true},
+		{`"done"`, false},
+		{"}", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsIncomplete(tt.input); got != tt.expected {
+			t.Errorf("IsIncomplete(%q) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMultiLineInput(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b\n};\nadd(1,\n2)\n"
+	out := runSession(input, false)
+	if out != ">>....>>..3\n>>" {
+		t.Errorf("unexpected session output: %q", out)
+	}
+	out = runSession("[1,\n2]\n", true)
+	if out != ">>..[1, 2]\n>>" {
+		t.Errorf("unexpected session output in compilation mode: %q", out)
+	}
+}
+
+func TestEmptyLineEndsIncompleteInput(t *testing.T) {
+	out := runSession("1 +\n\n1\n", false)
+	if !strings.Contains(out, "no prefix parse function") {
+		t.Errorf("expected a parse error, got %q", out)
+	}
+	if !strings.HasSuffix(out, "1\n>>") {
+		t.Errorf("session did not continue after the error: %q", out)
+	}
+}
+
+func TestMetaCommands(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "lib.bjs")
+	if err := os.WriteFile(script, []byte("let double = fn(x) {\n  x * 2\n};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1;\n:env\n", []string{"a = 1\n"}},
+		{"let a = 1;\n:reset\n:env\n", []string{"session reset\n", "no bindings\n"}},
+		{":load " + script + "\ndouble(21)\n", []string{"42\n"}},
+		{":tokens x;\n", []string{"1:1      IDENT      \"x\"\n", "1:2      ;          \";\"\n"}},
+		{"1 + 2 * 3\n:ast\n", []string{"(1 + (2 * 3))\n"}},
+		{":bytecode 1 + 2\n", []string{"0000 INTEGER 1\n", "0006 OpAdd\n"}},
+		{":engine vm\n:engine\n", []string{"engine: vm\n"}},
+		{":engine jit\n", []string{"unknown engine \"jit\", use eval or vm\n"}},
+		{":nope\n", []string{"unknown command :nope"}},
+		{":help\n", []string{":load file.bjs"}},
+		{"# only a comment\n:engine vm\n# again\n", []string{"engine: vm\n"}},
+	}
+	for _, tt := range tests {
+		out := runSession(tt.input, false)
+		for _, expected := range tt.expected {
+			if !strings.Contains(out, expected) {
+				t.Errorf("session %q: output does not contain %q\n%s", tt.input, expected, out)
+			}
+		}
+	}
+}
+
+func TestQuitStopsReading(t *testing.T) {
+	out := runSession(":quit\n1 + 2\n", false)
+	if out != ">>" {
+		t.Errorf("session did not stop after :quit: %q", out)
+	}
+}
+
+func TestCompiledModeKeepsBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 5;\nlet double = fn(x) { x * 2 };\ndouble(a)\n", []string{">>>>>>10\n"}},
+		{"let a = 1;\nlet a = a + 1;\na\n", []string{"2\n"}},
+		{"let a = 1;\n:env\n", []string{"a = 1\n"}},
+		{"let a = 1;\n:reset\n:env\n", []string{"no bindings\n"}},
+		{"let a = 1;\n:bytecode a\n", []string{"0000 OpGetGlobal 0\n"}},
+		// Inputs that fail at runtime keep the bindings made before the error, Inputs that do not compile
+		// leave no bindings behind
+		{"let b = 1; c;\nb\n", []string{"identifier not found: c", ">>1\n"}},
+		{"let b = 1; 1(); let d = 2;\nd\nb\n", []string{"not a function: INTEGER", "identifier not found: d", ">>1\n"}},
+		{"let b = 1; 1(); let d = 2;\nd = 3;\n:env\n", []string{"assignment to undeclared identifier: d", "b = 1\n>>"}},
+		{"let b = 1; break;\nb\n", []string{"break is only allowed inside a loop", "identifier not found: b"}},
+		{"let add = fn(a, b) {\n  a + b\n};\nadd(1,\n2)\n", []string{"3\n"}},
+		// Macros of one input are expanded in the later inputs
+		{"let sq = macro(x) { quote(unquote(x) * unquote(x)) };\nsq(3)\n", []string{"9\n"}},
+		{"let m = macro(x) { 1 };\nm(2)\n", []string{"macro m has to return back a quote, got INTEGER"}},
+	}
+	for _, tt := range tests {
+		out := runSession(tt.input, true)
+		for _, expected := range tt.expected {
+			if !strings.Contains(out, expected) {
+				t.Errorf("session %q: output does not contain %q\n%s", tt.input, expected, out)
+			}
+		}
+	}
+}
+
+func readEditorLine(t *testing.T, keys string, h *history) string {
+	t.Helper()
+	complete := func(prefix string) []string {
+		return completions(prefix, []string{"let", "len", "last", "fn"}, []string{"lengthy"})
+	}
+	e := newEditor(strings.NewReader(keys), &bytes.Buffer{}, h, complete)
+	line, err := e.ReadLine(">>")
+	if err != nil {
+		t.Fatalf("ReadLine(%q) failed: %s", keys, err)
+	}
+	return line
+}
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"1 + 2\r", "1 + 2"},
+		{"abc\x7f\r", "ab"},
+		{"ac\x1b[Db\r", "abc"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abc\x1b[D\x1b[D\x1b[3~\r", "ac"},
+		{"abc\x01\x0b\r", ""},
+		{"let x\x17\r", "let "},
+		{"abc\x1b[D\x15\r", "c"},
+		{"le\t\r", "le"},
+		{"la\t\r", "last"},
+		{"leng\t\r", "lengthy"},
+		{"f\t(\r", "fn("},
+	}
+	for _, tt := range tests {
+		if got := readEditorLine(t, tt.keys, &history{}); got != tt.expected {
+			t.Errorf("keys %q: wrong line. want=%q, got=%q", tt.keys, tt.expected, got)
+		}
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h := loadHistory(path)
+	readEditorLine(t, "let a = 1;\r", h)
+	readEditorLine(t, "a + 1\r", h)
+	readEditorLine(t, "a + 1\r", h)
+	readEditorLine(t, "   \r", h)
+
+	h = loadHistory(path)
+	if strings.Join(h.entries, "|") != "let a = 1;|a + 1" {
+		t.Fatalf("history not persisted: %q", h.entries)
+	}
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"\x1b[A\r", "a + 1"},
+		{"\x1b[A\x1b[A\r", "let a = 1;"},
+		{"x\x1b[A\x1b[B\r", "x"},
+		{"\x12let\r", "let a = 1;"},
+		{"\x12a\x12\r", "let a = 1;"},
+		{"\x12zzz\r", ""},
+		{"keep\x12a\x07\r", "keep"},
+		{"\x12+\x1b[C 2\r", "a + 1 2"},
+	}
+	for _, tt := range tests {
+		if got := readEditorLine(t, tt.keys, h); got != tt.expected {
+			t.Errorf("keys %q: wrong line. want=%q, got=%q", tt.keys, tt.expected, got)
+		}
+	}
+}
+
+func TestEditorEndOfInput(t *testing.T) {
+	e := newEditor(strings.NewReader("\x04"), &bytes.Buffer{}, &history{}, nil)
+	if _, err := e.ReadLine(">>"); err != io.EOF {
+		t.Errorf("Ctrl-D on an empty line should end the input. got=%v", err)
+	}
+	e = newEditor(strings.NewReader("abc\x03"), &bytes.Buffer{}, &history{}, nil)
+	if _, err := e.ReadLine(">>"); err != errInterrupted {
+		t.Errorf("Ctrl-C should interrupt the line. got=%v", err)
+	}
+}
+
+func TestRuntimeErrorsPrintStack(t *testing.T) {
+	input := "let f = fn() { 1 + true };\nf()\n"
+	for _, compilationMode := range []bool{false, true} {
+		out := runSession(input, compilationMode)
+		expected := []string{
+			"<repl 1>:1:18: type mismatch: INTEGER + BOOLEAN",
+			// The excerpt is from the input that declared the function
+			"    let f = fn() { 1 + true };\n                     ^\n",
+			"    at f (<repl 1>:1:18)\n    at <repl 2>:1:2",
+		}
+		for _, expected := range expected {
+			if !strings.Contains(out, expected) {
+				t.Errorf("session in compilation mode %t: output does not contain %q\n%s", compilationMode, expected, out)
+			}
+		}
+	}
+}
+
+func TestDivisionByZeroKeepsSessionRunning(t *testing.T) {
+	for _, compilationMode := range []bool{false, true} {
+		out := runSession("10 / 0\n1 + 1\n", compilationMode)
+		if !strings.Contains(out, "division by zero") || !strings.Contains(out, "2\n") {
+			t.Errorf("session in compilation mode %t did not continue after the error\n%s", compilationMode, out)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 /*
 	Token Configuration for language.
 	This file contains all of the tokens necessary for the language to work.
@@ -18,6 +23,7 @@ const (
 	IDENT     = "IDENT"
 	INT       = "INT"
 	FLOAT     = "FLOAT"
+	BIGINT    = "BIGINT"
 	STRING    = "STRING"
 	BANG      = "!"
 	ASSIGN    = "="
@@ -33,9 +39,19 @@ const (
 	NEQ       = "!="
 	AND       = "&&"
 	OR        = "||"
+	PERCENT   = "%"
+	POWER     = "**"
+	AMPERSAND = "&"
+	PIPE      = "|"
+	CARET     = "^"
+	TILDE     = "~"
+	LSHIFT    = "<<"
+	RSHIFT    = ">>"
+	URSHIFT   = ">>>"
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
@@ -51,11 +67,64 @@ const (
 	ELSE      = "ELSE"
 	RETURN    = "RETURN"
 	MACRO     = "MACRO"
+	WHILE     = "WHILE"
+	FOR       = "FOR"
+	IN        = "IN"
+	BREAK     = "BREAK"
+	CONTINUE  = "CONTINUE"
+	CONST     = "CONST"
+	THROW     = "THROW"
+	TRY       = "TRY"
+	CATCH     = "CATCH"
+	FINALLY   = "FINALLY"
+)
+
+// Assignment operators which combine an arithmetic operator with the assignment, And the increment and
+// decrement operators
+const (
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTARISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	INCREMENT       = "++"
+	DECREMENT       = "--"
 )
 
+// Arrow between the parameters and the body of an arrow function
+const ARROW = "=>"
+
+// Position of a token in the source, Line and Column start at 1 and Offset is the byte offset from the
+// start of the input. The zero value is an invalid position which is used for nodes created outside the parser.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
 type Token struct {
 	Type    Type
 	Literal string
+	Pos     Position
+}
+
+// IsValid returns back true if the position was recorded by the lexer
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as file:line:column, The file is left out when it is not known
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.File != "" {
+			return p.File
+		}
+		return "-"
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
 }
 
 var keywords = map[string]Type{
@@ -68,6 +137,30 @@ var keywords = map[string]Type{
 	"else":   ELSE,
 	"return": RETURN,
 	"macro":  MACRO,
+	// Loops, "of" is not a keyword so that it can still be used as a name outside of for loops
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"const":    CONST,
+	// Exceptions
+	"throw":   THROW,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	// JavaScript spelling of fn
+	"function": FUNCTION,
+}
+
+// Returns back the keywords of the language in sorted order, Used for completion in the REPL
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
 }
 
 func ReadIdent(ident string) Type {
@@ -0,0 +1,79 @@
+package virtualmachine
+
+import (
+	"compiler/diagnostic"
+	"compiler/object"
+)
+
+// Error of a throw statement, It carries the thrown value so that the catch block gets it back unchanged
+type thrownError struct {
+	value object.Object
+}
+
+func (e *thrownError) Error() string {
+	return object.ThrownMessage(e.value)
+}
+
+// RuntimeError is the error that stopped the program, Kind is the kind a catch block would have got. Stack has
+// the frames the error unwound through with the innermost first, The last frame is the one of the main program.
+type RuntimeError struct {
+	Kind    string
+	Message string
+	Stack   []diagnostic.Frame
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+// Returns back the error as a diagnostic at the position it happened along with its stack, Used to report the
+// error with the source
+func (e *RuntimeError) Diagnostic() *diagnostic.Diagnostic {
+	return &diagnostic.Diagnostic{Pos: e.Stack[0].Pos, Message: e.Message, Stack: e.Stack}
+}
+
+// Looks for the handler of the instruction that raised the error in the frames from the innermost one outwards,
+// The frames above the frame with the handler are dropped and the stack is cut back to the depth the handler
+// expects. Every dropped frame is added to the stack of the error, The error is returned back as a
+// *RuntimeError when no try statement catches it.
+func (vm *VirtualMachine) handleError(err error) error {
+	stack := []diagnostic.Frame{}
+	for {
+		frame := vm.currentFrame()
+		pos := frame.cl.Fn.Positions.PositionOf(frame.ip)
+		for _, h := range frame.cl.Fn.Handlers {
+			if frame.ip < h.Start || frame.ip >= h.End {
+				continue
+			}
+			vm.sp = frame.basePointer + frame.cl.Fn.NumLocals + h.Depth
+			frame.ip = h.Target - 1
+			// The frame of the catch block is named unless it is the main program
+			caught := diagnostic.Frame{Pos: pos}
+			if vm.framesIndex > 1 {
+				caught.Function = object.FunctionName(frame.cl.Fn.Name)
+			}
+			vm.push(exceptionOf(err, append(stack, caught)))
+			return nil
+		}
+		if vm.framesIndex == 1 {
+			return &RuntimeError{Kind: object.KindOf(err), Message: err.Error(), Stack: append(stack, diagnostic.Frame{Pos: pos})}
+		}
+		stack = append(stack, diagnostic.Frame{Function: object.FunctionName(frame.cl.Fn.Name), Pos: pos})
+		vm.popFrame()
+	}
+}
+
+// Returns back the value the catch block gets for the error, Runtime errors are turned into exceptions. The
+// stack is kept by exceptions which do not have a stack yet.
+func exceptionOf(err error, stack []diagnostic.Frame) object.Object {
+	thrown, ok := err.(*thrownError)
+	if !ok {
+		exception := object.NewException(object.KindOf(err), err.Error())
+		exception.Stack = stack
+		return exception
+	}
+	if exception, ok := thrown.value.(*object.Exception); ok && len(exception.Stack) == 0 {
+		exception.Stack = stack
+	}
+	return thrown.value
+}
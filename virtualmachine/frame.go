@@ -0,0 +1,26 @@
+package virtualmachine
+
+import (
+	"compiler/code"
+	"compiler/object"
+)
+
+// Frame is the call frame of a closure, It keeps its own instruction pointer so that the virtual machine
+// can continue with the caller after the closure returns. The base pointer is the stack pointer before the
+// call, the locals of the closure are stored on the stack starting from it.
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+}
+
+// Creates a new frame for the closure, The instruction pointer starts before the first instruction since the
+// run loop increments it before fetching.
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+// Returns back the instructions of the closure the frame is executing
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
@@ -6,34 +6,79 @@ import (
 	"compiler/constants"
 	"compiler/object"
 	"fmt"
+	"math"
+	"math/big"
 )
 
-// Defining stacksize to also check with stack overflow
-// Limited stack size has been used, for in depth recursive operations this stack size can be increased
-const StackSize = 2048
+// Upper limit on the depth of nested calls, The main program takes the first frame
+const MaxFrames = object.MaxCallDepth + 1
+
+// Defining stacksize to also check with stack overflow, Every frame has room for 32 values so that recursion
+// runs out of frames before it runs out of stack like in the evaluator
+const StackSize = MaxFrames * 32
+
+// Upper limit on the number of global bindings, Matches the two byte operand of OpGetGlobal and OpSetGlobal
+const GlobalsSize = 65536
 
 // Setting global values of True and False as they are immutable and do not change
 // Defining them everytime gains memory space and has to gc it again and again
 var True = &object.Boolean{Value: true}
 var False = &object.Boolean{Value: false}
+var Null = &object.Null{}
 
 type VirtualMachine struct {
-	constants    []object.Object
-	instructions code.Instructions
-	stack        []object.Object // Virtual machine stack
-	sp           int             // StackPointer always points to the top of the stack
+	constants   []object.Object
+	stack       []object.Object // Virtual machine stack
+	sp          int             // StackPointer always points to the top of the stack
+	globals     []object.Object // Values of the global bindings indexed by symbol index
+	frames      []*Frame        // Call frames, The main program runs in the first frame
+	framesIndex int
 }
 
 // Creates new virtual machine and returns back for execution
 func New(bytecode *compiler.ByteCode) *VirtualMachine {
+	// The main program is executed like a closure without parameters in the first frame
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, Handlers: bytecode.Handlers, Positions: bytecode.Positions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
 	// Create a virtual machine using the basic params given in the bytecode
 	// Set the stack size to the one defined above.
 	return &VirtualMachine{
-		instructions: bytecode.Instructions,
-		constants:    bytecode.Constants,
-		stack:        make([]object.Object, StackSize),
-		sp:           0,
+		constants:   bytecode.Constants,
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		globals:     make([]object.Object, GlobalsSize),
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// Creates a virtual machine which uses the given globals store, Used by the REPL so that global bindings
+// survive between inputs. The store has to be GlobalsSize long.
+func NewWithGlobalsStore(bytecode *compiler.ByteCode, globals []object.Object) *VirtualMachine {
+	vm := New(bytecode)
+	vm.globals = globals
+	return vm
+}
+
+func (vm *VirtualMachine) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VirtualMachine) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return object.RangeErrorf("stack overflow")
 	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VirtualMachine) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
 }
 
 // Returs back the object located on the stacktop
@@ -44,11 +89,32 @@ func (vm *VirtualMachine) StackTop() object.Object {
 	return vm.stack[vm.sp-1]
 }
 
-// Returns back error and runs the program in Fetch, Decode, Execute cycle
+// Runs the program and returns back the error that stopped it as a *RuntimeError, Errors raised inside of a
+// try statement are caught by its handler and the program continues there
 func (vm *VirtualMachine) Run() error {
-	// Setup instruction pointer and start going through the pointer
-	for ip := 0; ip < len(vm.instructions); ip++ {
-		op := code.Opcode(vm.instructions[ip])
+	for {
+		err := vm.run()
+		if err == nil {
+			return nil
+		}
+		if err = vm.handleError(err); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns back error and runs the program in Fetch, Decode, Execute cycle
+func (vm *VirtualMachine) run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+	// Setup instruction pointer and start going through the pointer, The instruction pointer lives in the
+	// current frame so that calls and returns can switch between frames
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
 		// Get the opcode and then start decoding in execute cycle
 		switch op {
 		case code.OpBang:
@@ -61,9 +127,14 @@ func (vm *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpBitNot:
+			err := vm.executeBitNotOperator()
+			if err != nil {
+				return err
+			}
 		case code.OpConstant:
-			constIndex := code.ReadUint16(vm.instructions[ip+1:])
-			ip += 2
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
 			err := vm.push(vm.constants[constIndex])
 			if err != nil {
 				return err
@@ -78,23 +149,482 @@ func (vm *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpAdd, code.OpDiv, code.OpMul, code.OpSub:
+		case code.OpAdd, code.OpDiv, code.OpMul, code.OpSub, code.OpMod, code.OpPow, code.OpBitAnd, code.OpBitOr,
+			code.OpBitXor, code.OpShiftLeft, code.OpShiftRight, code.OpUnsignedShiftRight:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
 			}
-		case code.OpEqual, code.OpGreaterThan, code.OpNotEqual:
+		case code.OpJumpIfFalseOrPop, code.OpJumpIfTrueOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			truthy := isTruthy(vm.StackTop())
+			if truthy == (op == code.OpJumpIfTrueOrPop) {
+				// The loop increments ip, so we stop right before the target
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterThanOrEqual, code.OpLessThan,
+			code.OpLessThanOrEqual:
 			err := vm.executeComparison(op)
 			if err != nil {
 				return err
 			}
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+		case code.OpNull:
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			declareSlot(&vm.globals[globalIndex], vm.pop())
+		case code.OpAssignGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			err := assignSlot(&vm.globals[globalIndex], vm.pop())
+			if err != nil {
+				return err
+			}
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			value, err := readSlot(vm.globals[globalIndex])
+			if err != nil {
+				return err
+			}
+			err = vm.push(value)
+			if err != nil {
+				return err
+			}
+		case code.OpGetLateGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			name := vm.constants[code.ReadUint16(ins[ip+3:])].(*object.String)
+			vm.currentFrame().ip += 4
+			if vm.globals[globalIndex] == nil {
+				return object.ReferenceErrorf("identifier not found: %s", name.Value)
+			}
+			value, err := readSlot(vm.globals[globalIndex])
+			if err != nil {
+				return err
+			}
+			err = vm.push(value)
+			if err != nil {
+				return err
+			}
+		case code.OpAssignConstant:
+			name := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.String)
+			vm.currentFrame().ip += 2
+			cell := vm.pop().(*object.Cell)
+			if _, ok := cell.Value.(*object.Uninitialized); ok {
+				return object.ReferenceErrorf("assignment to undeclared identifier: %s", name.Value)
+			}
+			return object.TypeErrorf("assignment to constant variable: %s", name.Value)
+		case code.OpCaptureGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			err := vm.push(captureCell(&vm.globals[globalIndex]))
+			if err != nil {
+				return err
+			}
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			declareSlot(&vm.stack[frame.basePointer+int(localIndex)], vm.pop())
+		case code.OpAssignLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			err := assignSlot(&vm.stack[frame.basePointer+int(localIndex)], vm.pop())
+			if err != nil {
+				return err
+			}
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			value, err := readSlot(vm.stack[frame.basePointer+int(localIndex)])
+			if err != nil {
+				return err
+			}
+			err = vm.push(value)
+			if err != nil {
+				return err
+			}
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			value, err := readSlot(vm.currentFrame().cl.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+			err = vm.push(value)
+			if err != nil {
+				return err
+			}
+		case code.OpAssignFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := assignSlot(&vm.currentFrame().cl.Free[freeIndex], vm.pop())
+			if err != nil {
+				return err
+			}
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			frame := vm.currentFrame()
+			err := vm.push(captureCell(&vm.stack[frame.basePointer+int(localIndex)]))
+			if err != nil {
+				return err
+			}
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.push(vm.currentFrame().cl.Free[freeIndex])
+			if err != nil {
+				return err
+			}
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.push(object.Builtins[builtinIndex].Builtin)
+			if err != nil {
+				return err
+			}
+		case code.OpCurrentClosure:
+			err := vm.push(vm.currentFrame().cl)
+			if err != nil {
+				return err
+			}
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+			err := vm.pushClosure(int(constIndex), int(numFree))
+			if err != nil {
+				return err
+			}
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.executeCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+			if vm.framesIndex == 1 {
+				// Return at the top level ends the program with the value as result
+				vm.stack[vm.sp] = returnValue
+				return nil
+			}
+			frame := vm.popFrame()
+			// The closure itself sits right below the base pointer and is removed as well
+			vm.sp = frame.basePointer - 1
+			err := vm.push(returnValue)
+			if err != nil {
+				return err
+			}
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			err := vm.push(Null)
+			if err != nil {
+				return err
+			}
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+			err := vm.push(array)
+			if err != nil {
+				return err
+			}
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			err := vm.executeIndexExpression(left, index)
+			if err != nil {
+				return err
+			}
+		case code.OpSetIndex:
+			keepOld := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err := vm.executeSetIndex(keepOld == 1)
+			if err != nil {
+				return err
+			}
+		case code.OpDup2:
+			err := vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
+			err = vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
+		case code.OpIter:
+			keys := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			iterator, err := object.NewIterator(vm.pop(), keys == 1)
+			if err != nil {
+				return err
+			}
+			err = vm.push(iterator)
+			if err != nil {
+				return err
+			}
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			// The iterator stays on the stack until the loop is done
+			iterator := vm.StackTop().(*object.Iterator)
+			value, ok := iterator.Next()
+			if !ok {
+				vm.currentFrame().ip = pos - 1
+				continue
+			}
+			err := vm.push(value)
+			if err != nil {
+				return err
+			}
 		case code.OpPop:
 			vm.pop()
+		case code.OpThrow:
+			return &thrownError{value: vm.pop()}
 		}
 	}
 	return nil
 }
 
+// Calls the closure or builtin which sits below the arguments on the stack
+func (vm *VirtualMachine) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return object.TypeErrorf("not a function: %s", callee.Type())
+	}
+}
+
+// The arguments become the first locals of the new frame and the stack pointer is moved past the remaining
+// locals.
+func (vm *VirtualMachine) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return object.TypeErrorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if frame.basePointer+cl.Fn.NumLocals >= StackSize {
+		return object.RangeErrorf("stack overflow")
+	}
+	err := vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+// Calls the builtin with the arguments on the stack and replaces the builtin and the arguments with the
+// result, Errors returned back by builtins stop the program the same way they do in the evaluator.
+func (vm *VirtualMachine) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1
+	if err, ok := result.(*object.Error); ok {
+		return &object.KindError{Kind: err.Kind, Message: err.Message}
+	}
+	if result == nil {
+		return vm.push(Null)
+	}
+	return vm.push(result)
+}
+
+// Wraps the compiled function constant into a closure, The free variables are taken off the stack
+func (vm *VirtualMachine) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return object.TypeErrorf("not a function: %+v", constant)
+	}
+	free := make([]object.Object, numFree)
+	for i := 0; i < numFree; i++ {
+		free[i] = vm.stack[vm.sp-numFree+i]
+	}
+	vm.sp = vm.sp - numFree
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+// Builds an array out of the stack elements between start and end
+func (vm *VirtualMachine) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+	for i := startIndex; i < endIndex; i++ {
+		elements[i-startIndex] = vm.stack[i]
+	}
+	return &object.Array{Elements: elements}
+}
+
+// Builds a hash out of the stack elements between start and end, The keys and values alternate on the stack
+func (vm *VirtualMachine) buildHash(startIndex, endIndex int) (object.Object, error) {
+	pairs := make(map[object.HashKey]object.HashPair)
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, object.TypeErrorf("unusable as hash key: %s", key.Type())
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+	return &object.Hash{Pairs: pairs}, nil
+}
+
+// Stores the value on top of the stack at the index below it, The array or hash sits below the index. The
+// assigned value or with keepOld the old value at the index is pushed as result.
+func (vm *VirtualMachine) executeSetIndex(keepOld bool) error {
+	value := vm.pop()
+	index := vm.pop()
+	left := vm.pop()
+	var old object.Object
+	if keepOld {
+		if err := vm.executeIndexExpression(left, index); err != nil {
+			return err
+		}
+		old = vm.pop()
+	}
+	if err := object.SetIndex(left, index, value); err != nil {
+		return err
+	}
+	if keepOld {
+		return vm.push(old)
+	}
+	return vm.push(value)
+}
+
+// Free variables and captured locals are kept in cells, Reading them returns back the value of the cell
+func unwrapCell(obj object.Object) object.Object {
+	if cell, ok := obj.(*object.Cell); ok {
+		return cell.Value
+	}
+	return obj
+}
+
+// Returns back the value of the binding in the slot, Names of a block with function declarations can not be
+// read before their let statement ran
+func readSlot(obj object.Object) (object.Object, error) {
+	value := unwrapCell(obj)
+	if uninitialized, ok := value.(*object.Uninitialized); ok {
+		return nil, object.ReferenceErrorf("identifier not found: %s", uninitialized.Name)
+	}
+	return value, nil
+}
+
+// Assigns the value to the binding in the slot, A captured binding is assigned through its cell so the
+// closures that captured it see the new value
+func assignSlot(slot *object.Object, value object.Object) error {
+	if uninitialized, ok := unwrapCell(*slot).(*object.Uninitialized); ok {
+		return object.ReferenceErrorf("assignment to undeclared identifier: %s", uninitialized.Name)
+	}
+	if cell, ok := (*slot).(*object.Cell); ok {
+		cell.Value = value
+		return nil
+	}
+	*slot = value
+	return nil
+}
+
+// Declares a new binding in the slot, A binding which closures captured while it was uninitialized is declared
+// in their cell. Any other binding is replaced so closures of an earlier binding keep it.
+func declareSlot(slot *object.Object, value object.Object) {
+	if cell, ok := (*slot).(*object.Cell); ok {
+		if _, ok := cell.Value.(*object.Uninitialized); ok {
+			cell.Value = value
+			return
+		}
+	}
+	*slot = value
+}
+
+// Moves the binding in the slot into a cell unless it is in one already and returns back the cell
+func captureCell(slot *object.Object) *object.Cell {
+	cell, ok := (*slot).(*object.Cell)
+	if !ok {
+		cell = &object.Cell{Value: *slot}
+		*slot = cell
+	}
+	return cell
+}
+
+// Indexes arrays by integers and hashes by hashable keys, Missing elements result in null
+func (vm *VirtualMachine) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == constants.ARRAY_OBJECT && index.Type() == constants.INTEGER_OBJECT:
+		return vm.executeArrayIndex(left, index)
+	case left.Type() == constants.HASH_OBJECT:
+		return vm.executeHashIndex(left, index)
+	case object.HasMembers(left) && index.Type() == constants.STRING_OBJECT:
+		member, err := object.GetMember(left, index.(*object.String).Value)
+		if err != nil {
+			return err
+		}
+		return vm.push(member)
+	default:
+		return object.TypeErrorf("index operator has wrong type that is not supported yet %s", left.Type())
+	}
+}
+
+func (vm *VirtualMachine) executeArrayIndex(array, index object.Object) error {
+	arrayObject := array.(*object.Array)
+	idx := index.(*object.Integer).Value
+	max := int64(len(arrayObject.Elements) - 1)
+	if idx < 0 || idx > max {
+		return vm.push(Null)
+	}
+	return vm.push(arrayObject.Elements[idx])
+}
+
+func (vm *VirtualMachine) executeHashIndex(hash, index object.Object) error {
+	hashObject := hash.(*object.Hash)
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return object.TypeErrorf("unusable as hash key: %s", index.Type())
+	}
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return vm.push(Null)
+	}
+	return vm.push(pair.Value)
+}
+
 // Bang operator adds the operand and makes opposite type back and pushes back to the stack
 func (vm *VirtualMachine) executeBangOperator() error {
 	operand := vm.pop()
@@ -103,6 +633,8 @@ func (vm *VirtualMachine) executeBangOperator() error {
 		return vm.push(False)
 	case False:
 		return vm.push(True)
+	case Null:
+		return vm.push(True)
 	default:
 		return vm.push(False)
 	}
@@ -111,28 +643,86 @@ func (vm *VirtualMachine) executeBangOperator() error {
 // Minus operator is returned back and pushed to the stack
 func (vm *VirtualMachine) executeMinusOperator() error {
 	operand := vm.pop()
-	if operand.Type() != constants.INTEGER_OBJECT {
-		return fmt.Errorf("type is unsupported %s", operand.Type())
+	switch operand := operand.(type) {
+	case *object.Integer:
+		negated, err := object.NegateInteger(operand.Value)
+		if err != nil {
+			return err
+		}
+		return vm.push(negated)
+	case *object.BigInt:
+		return vm.push(&object.BigInt{Value: new(big.Int).Neg(operand.Value)})
+	case *object.Float:
+		return vm.push(&object.Float{Value: -operand.Value})
+	default:
+		return object.TypeErrorf("unknown operator: -%s", operand.Type())
+	}
+}
+
+// Inverts the bits of the integer on top of the stack, Same as in the evaluator
+func (vm *VirtualMachine) executeBitNotOperator() error {
+	operand := vm.pop()
+	switch operand := operand.(type) {
+	case *object.Integer:
+		return vm.push(&object.Integer{Value: ^operand.Value})
+	case *object.BigInt:
+		return vm.push(&object.BigInt{Value: new(big.Int).Not(operand.Value)})
+	default:
+		return object.TypeErrorf("unknown operator: ~%s", operand.Type())
 	}
-	value := operand.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -value})
 }
 
 // Checks for comparision checks and returns back error if it exist
 func (vm *VirtualMachine) executeComparison(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
-	if left.Type() == constants.INTEGER_OBJECT || right.Type() == constants.INTEGER_OBJECT {
+	if left.Type() == constants.INTEGER_OBJECT && right.Type() == constants.INTEGER_OBJECT {
 		return vm.executeIntegerComparision(op, left, right)
 	}
+	if object.IsInteger(left) && object.IsInteger(right) {
+		return vm.executeBigIntComparision(op, left, right)
+	}
+	if object.IsNumber(left) && object.IsNumber(right) {
+		return vm.executeFloatComparision(op, left, right)
+	}
 	switch op {
 	case code.OpEqual:
 		return vm.push(nativeBoolToBooleanObject(right == left))
 	case code.OpNotEqual:
 		return vm.push(nativeBoolToBooleanObject(right != left))
 	default:
-		return fmt.Errorf("operator not supported: %d %s %s", op, left.Type(), right.Type())
+		return operatorError(op, left, right)
+	}
+}
+
+// Symbols of the infix operators, Used for reporting errors with the same message as the evaluator
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:                "+",
+	code.OpSub:                "-",
+	code.OpMul:                "*",
+	code.OpDiv:                "/",
+	code.OpMod:                "%",
+	code.OpPow:                "**",
+	code.OpBitAnd:             "&",
+	code.OpBitOr:              "|",
+	code.OpBitXor:             "^",
+	code.OpShiftLeft:          "<<",
+	code.OpShiftRight:         ">>",
+	code.OpUnsignedShiftRight: ">>>",
+	code.OpEqual:              "==",
+	code.OpNotEqual:           "!=",
+	code.OpGreaterThan:        ">",
+	code.OpGreaterThanOrEqual: ">=",
+	code.OpLessThan:           "<",
+	code.OpLessThanOrEqual:    "<=",
+}
+
+// Returns back the error for an operator that can not be applied on the operands
+func operatorError(op code.Opcode, left, right object.Object) error {
+	if left.Type() != right.Type() {
+		return object.TypeErrorf("type mismatch: %s %s %s", left.Type(), infixOperators[op], right.Type())
 	}
+	return object.TypeErrorf("unknown operator: %s %s %s", left.Type(), infixOperators[op], right.Type())
 }
 
 // Checks exclusive integer comparision
@@ -146,11 +736,72 @@ func (vm *VirtualMachine) executeIntegerComparision(op code.Opcode, left, right
 		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
 	case code.OpGreaterThan:
 		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	default:
+		return fmt.Errorf("operator not supported: %d", op)
+	}
+}
+
+// Compares two integers where at least one is a big integer, The integer is promoted to a big integer
+func (vm *VirtualMachine) executeBigIntComparision(op code.Opcode, left, right object.Object) error {
+	cmp := object.ToBigInt(left).Cmp(object.ToBigInt(right))
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp != 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(cmp > 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp >= 0))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(cmp < 0))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(cmp <= 0))
+	default:
+		return fmt.Errorf("operator not supported: %d", op)
+	}
+}
+
+// Compares two numbers where at least one is a float, The integer is promoted to float
+func (vm *VirtualMachine) executeFloatComparision(op code.Opcode, left, right object.Object) error {
+	leftValue := object.ToFloat(left)
+	rightValue := object.ToFloat(right)
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue == leftValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue <= rightValue))
 	default:
 		return fmt.Errorf("operator not supported: %d", op)
 	}
 }
 
+// Checks if the object counts as true in conditions, false and null are the only falsy values
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
+
 // Returns back boolean operator in form of Object which is pointer to
 // the true or false immutable objects in memory
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
@@ -168,7 +819,26 @@ func (vm *VirtualMachine) executeBinaryOperation(op code.Opcode) error {
 	if right.Type() == constants.INTEGER_OBJECT && left.Type() == constants.INTEGER_OBJECT {
 		return vm.executeBinaryIntegerOperation(op, left, right)
 	}
-	return fmt.Errorf("unsupported types %s %s", left.Type(), right.Type())
+	if object.IsInteger(left) && object.IsInteger(right) {
+		return vm.executeBinaryBigIntOperation(op, left, right)
+	}
+	if object.IsNumber(left) && object.IsNumber(right) {
+		return vm.executeBinaryFloatOperation(op, left, right)
+	}
+	if left.Type() == constants.STRING_OBJECT && right.Type() == constants.STRING_OBJECT {
+		return vm.executeBinaryStringOperation(op, left, right)
+	}
+	return operatorError(op, left, right)
+}
+
+// Strings only support concatenation, Same as in the evaluator
+func (vm *VirtualMachine) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
+	if op != code.OpAdd {
+		return operatorError(op, left, right)
+	}
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+	return vm.push(&object.String{Value: leftValue + rightValue})
 }
 
 // Does the operations on left and right operator and then pushed them on the VM stack and returns back error
@@ -176,7 +846,39 @@ func (vm *VirtualMachine) executeBinaryOperation(op code.Opcode) error {
 func (vm *VirtualMachine) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
-	var result int64
+	operator, ok := infixOperators[op]
+	if !ok {
+		return fmt.Errorf("unknown integer operator : %d", op)
+	}
+	result, err := object.IntegerArithmetic(operator, leftVal, rightVal)
+	if err != nil {
+		return err
+	}
+	return vm.push(result)
+}
+
+// Does the arithmetic on two integers where at least one is a big integer, The integer is promoted to a big
+// integer, Unsupported operators are reported with the types of the operands before the promotion
+func (vm *VirtualMachine) executeBinaryBigIntOperation(op code.Opcode, left, right object.Object) error {
+	operator, ok := infixOperators[op]
+	if !ok {
+		return fmt.Errorf("unknown big integer operator : %d", op)
+	}
+	if op == code.OpUnsignedShiftRight {
+		return object.TypeErrorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+	result, err := object.BigArithmetic(operator, object.ToBigInt(left), object.ToBigInt(right))
+	if err != nil {
+		return err
+	}
+	return vm.push(result)
+}
+
+// Does the arithmetic on two numbers where at least one is a float, The integer is promoted to float
+func (vm *VirtualMachine) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftVal := object.ToFloat(left)
+	rightVal := object.ToFloat(right)
+	var result float64
 	switch op {
 	case code.OpAdd:
 		result = leftVal + rightVal
@@ -186,16 +888,20 @@ func (vm *VirtualMachine) executeBinaryIntegerOperation(op code.Opcode, left, ri
 		result = leftVal / rightVal
 	case code.OpMul:
 		result = leftVal * rightVal
+	case code.OpMod:
+		result = math.Mod(leftVal, rightVal)
+	case code.OpPow:
+		result = math.Pow(leftVal, rightVal)
 	default:
-		return fmt.Errorf("unknown integer operator : %d", op)
+		return object.TypeErrorf("unknown operator: %s %s %s", left.Type(), infixOperators[op], right.Type())
 	}
-	return vm.push(&object.Integer{Value: result})
+	return vm.push(&object.Float{Value: result})
 }
 
 // Pushes the object to stack of Virtual machine and increments the stackpointer
 func (vm *VirtualMachine) push(o object.Object) error {
 	if vm.sp >= StackSize {
-		return fmt.Errorf("stack overflow")
+		return object.RangeErrorf("stack overflow")
 	}
 	vm.stack[vm.sp] = o
 	vm.sp++
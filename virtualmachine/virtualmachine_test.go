@@ -3,6 +3,7 @@ package virtualmachine
 import (
 	"compiler/ast"
 	"compiler/compiler"
+	"compiler/diagnostic"
 	"compiler/lexer"
 	"compiler/object"
 	"compiler/parser"
@@ -58,12 +59,73 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 		if err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {
 			t.Errorf("test boolean object failed %s", err)
 		}
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null: %T (%+v)", actual, actual)
+		}
+	case string:
+		err := testStringObject(expected, actual)
+		if err != nil {
+			t.Errorf("testStringObject failed: %s", err)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array: %T (%+v)", actual, actual)
+			return
+		}
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+			return
+		}
+		for i, expectedElem := range expected {
+			err := testIntegerObject(int64(expectedElem), array.Elements[i])
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+	case map[object.HashKey]int64:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not Hash. got=%T (%+v)", actual, actual)
+			return
+		}
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf("hash has wrong number of Pairs. want=%d, got=%d", len(expected), len(hash.Pairs))
+			return
+		}
+		for expectedKey, expectedValue := range expected {
+			pair, ok := hash.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+			}
+			err := testIntegerObject(expectedValue, pair.Value)
+			if err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+	}
+}
+
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
 	}
+	return nil
 }
 
 func testBooleanObject(expected bool, actual object.Object) error {
@@ -187,3 +249,689 @@ func TestBooleanExpressions(t *testing.T) {
 	}
 	runVmTests(t, tests)
 }
+
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%f, want=%f", result.Value, expected)
+	}
+	return nil
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"3.14", 3.14},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"10 / 4.0", 2.5},
+		{"2.5 * 2", 5.0},
+		{"1 - 0.25", 0.75},
+		{"(1.5 + 2) * -2", -7.0},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1.0 == 1", true},
+		{"1 != 1.0", false},
+		{"0.1 + 0.2 == 0.3", false},
+	}
+	runVmTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 <= 2", true},
+		{"3 >= 4 || 4 >= 3", true},
+		{"1.5 <= 1", false},
+		{"2 >= 2.0", true},
+		{"1 && 2", 2},
+		{"false && 2", false},
+		{"1 || 2", 1},
+		{"false || 2", 2},
+		{"1 + 2 > 2 && !false", true},
+		// The right side must not be evaluated, otherwise the division by zero would fail
+		{"false && 1 / 0", false},
+		{"true || 1 / 0", true},
+	}
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (true) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else { 20 } ", 20},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 > 2) { 10 }", Null},
+		{"if (false) { 10 }", Null},
+		{"!(if (false) { 5; })", true},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (true) { let a = 5; }", Null},
+	}
+	runVmTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = 2; one + two", 3},
+		{"let one = 1; let two = one + one; one + two", 3},
+		{"let one = 1; one = one + 1; one", 2},
+		// The let inside of the block declares a new name which shadows the global until the block ends
+		{"let a = 5; if (a > 2) { let a = a * 2; }; a", 5},
+		{"let a = 5; if (a > 2) { a = a * 2; }; a", 10},
+	}
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"let one = fn() { 1; }; let two = fn() { 2; }; one() + two()", 3},
+		{"let a = fn() { 1 }; let b = fn() { a() + 1 }; let c = fn() { b() + 1 }; c();", 3},
+		{"let earlyExit = fn() { return 99; 100; }; earlyExit();", 99},
+		{"let noReturn = fn() { }; noReturn();", Null},
+		{"let noValue = fn() { let a = 1; }; noValue();", Null},
+		{"let returnsOne = fn() { 1; }; let returnsOneReturner = fn() { returnsOne; }; returnsOneReturner()();", 1},
+		{"let identity = fn(a) { a; }; identity(4);", 4},
+		{"let sum = fn(a, b) { a + b; }; sum(1, 2);", 3},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", 10},
+		{"let globalNum = 10; let sum = fn(a, b) { let c = a + b; c + globalNum; }; let outer = fn() { sum(1, 2) + sum(3, 4) + globalNum; }; outer() + globalNum;", 50},
+		{"return 10; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+	}
+	runVmTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{"let newClosure = fn(a) { fn() { a; }; }; let closure = newClosure(99); closure();", 99},
+		{"let newAdder = fn(a, b) { fn(c) { a + b + c }; }; let adder = newAdder(1, 2); adder(8);", 11},
+		{"let newAdder = fn(a, b) { let c = a + b; fn(d) { c + d }; }; let adder = newAdder(1, 2); adder(8);", 11},
+		{`let newAdderOuter = fn(a, b) {
+			let c = a + b;
+			fn(d) {
+				let e = d + c;
+				fn(f) { e + f; };
+			};
+		};
+		let newAdderInner = newAdderOuter(1, 2)
+		let adder = newAdderInner(3);
+		adder(8);`, 14},
+		{`let newClosure = fn(a, b) {
+			let one = fn() { a; };
+			let two = fn() { b; };
+			fn() { one() + two(); };
+		};
+		let closure = newClosure(9, 90);
+		closure();`, 99},
+	}
+	runVmTests(t, tests)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let countDown = fn(x) {
+			if (x == 0) {
+				return 0;
+			} else {
+				countDown(x - 1);
+			}
+		};
+		countDown(1);`, 0},
+		{`let wrapper = fn() {
+			let countDown = fn(x) {
+				if (x == 0) {
+					return 0;
+				} else {
+					countDown(x - 1);
+				}
+			};
+			countDown(1);
+		};
+		wrapper();`, 0},
+		{`let fibonacci = fn(x) {
+			if (x == 0) {
+				return 0;
+			} else {
+				if (x == 1) {
+					return 1;
+				} else {
+					fibonacci(x - 1) + fibonacci(x - 2);
+				}
+			}
+		};
+		fibonacci(15);`, 610},
+	}
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctionsWithWrongArguments(t *testing.T) {
+	tests := []vmTestCase{
+		{"fn() { 1; }(1);", "wrong number of arguments: want=0, got=1"},
+		{"fn(a) { a; }();", "wrong number of arguments: want=1, got=0"},
+		{"fn(a, b) { a + b; }(1);", "wrong number of arguments: want=2, got=1"},
+		{"let a = 1; a(2);", "not a function: INTEGER"},
+		{"let loop = fn(x) { loop(x + 1) }; loop(0);", "stack overflow"},
+		{"let a = 10; a / (5 - 5);", "division by zero"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error but resulted in none.")
+		}
+		if err.Error() != tt.expected {
+			t.Fatalf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"bjs"`, "bjs"},
+		{`"b" + "js"`, "bjs"},
+		{`"b" + "j" + "s"`, "bjs"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", []int{}},
+		{"[1, 2, 3]", []int{1, 2, 3}},
+		{"[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"{}", map[object.HashKey]int64{}},
+		{"{1: 2, 2: 3}", map[object.HashKey]int64{
+			(&object.Integer{Value: 1}).HashKey(): 2,
+			(&object.Integer{Value: 2}).HashKey(): 3,
+		}},
+		{"{1 + 1: 2 * 2, 3 + 3: 4 * 4}", map[object.HashKey]int64{
+			(&object.Integer{Value: 2}).HashKey(): 4,
+			(&object.Integer{Value: 6}).HashKey(): 16,
+		}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][0 + 2]", 3},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"[][0]", Null},
+		{"[1, 2, 3][99]", Null},
+		{"[1][-1]", Null},
+		{"{1: 1, 2: 2}[1]", 1},
+		{"{1: 1, 2: 2}[2]", 2},
+		{"{1: 1}[0]", Null},
+		{"{}[0]", Null},
+		{`{"one": 1}["o" + "ne"]`, 1},
+	}
+	runVmTests(t, tests)
+}
+
+func TestIndexErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"{[1]: 1}", "unusable as hash key: ARRAY"},
+		{"{1: 1}[fn() { 1 }]", "unusable as hash key: FUNCTION"},
+		{"1[0]", "index operator has wrong type that is not supported yet INTEGER"},
+		{"[1].size", "ARRAY has no property size"},
+		{`"abc".push(1)`, "STRING has no property push"},
+		{"true.length", "index operator has wrong type that is not supported yet BOOLEAN"},
+		{`[1].join(1)`, "argument to join is invalid. got=INTEGER"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error but resulted in none.")
+		}
+		if err.Error() != tt.expected {
+			t.Fatalf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`prints("hello", "world!")`, Null},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, Null},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, Null},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, Null},
+		{`push([], 1)`, []int{1}},
+		{`let sum = fn(arr) { if (len(arr) == 0) { 0 } else { first(arr) + sum(rest(arr)) } }; sum([1, 2, 3])`, 6},
+		{`let wrapper = fn() { len }; wrapper()("abc")`, 3},
+	}
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFunctionErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`first(1)`, "argument to first is invalid. got=INTEGER"},
+		{`push(1, 1)`, "argument to push is invalid. got=INTEGER"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error but resulted in none.")
+		}
+		if err.Error() != tt.expected {
+			t.Fatalf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestGlobalsStoreIsShared(t *testing.T) {
+	globals := make([]object.Object, GlobalsSize)
+	comp := compiler.New()
+	if err := comp.Compile(parse("let a = 40;")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	if err := NewWithGlobalsStore(comp.ByteCode(), globals).Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	comp = compiler.NewWithState(comp.SymbolTable(), comp.ByteCode().Constants)
+	if err := comp.Compile(parse("a + 2")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := NewWithGlobalsStore(comp.ByteCode(), globals)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testExpectedObject(t, 42, vm.LastPoppedStackElem())
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while (i < 10) { i = i + 1; }; i", 10},
+		{"let sum = 0; for (let i = 1; i <= 4; i++) { sum = sum + i; }; sum", 10},
+		{"let i = 0; for (;;) { i = i + 1; if (i == 5) { break; } }; i", 5},
+		{"let sum = 0; for (let i = 0; i < 6; i++) { if (i == 2) { continue; } sum = sum + i; }; sum", 13},
+		{"let sum = 0; for (x of [1, 2, 3]) { sum = sum + x; }; sum", 6},
+		{"let sum = 0; for (i in [5, 5, 5]) { sum = sum + i; }; sum", 3},
+		{`let s = ""; for (k in {"b": 2, "a": 1}) { s = s + k; }; s`, "ab"},
+		{`let s = ""; for (c of "abc") { s = c + s; }; s`, "cba"},
+		{"let find = fn(xs) { for (x of xs) { if (x > 2) { return x; } } 0 }; find([1, 3, 5])", 3},
+		{"let count = 0; for (i of [1, 2]) { for (j of [1, 2, 3]) { if (j == 2) { break; } count = count + 1; } }; count", 2},
+		{"let f = fn() { let n = 0; while (n < 3) { n = n + 1; } n }; f()", 3},
+		{"let f = fn() { for (x of [1, 2]) { x } }; f()", Null},
+		{"if (true) { for (x of [1]) { } }", Null},
+	}
+	runVmTests(t, tests)
+}
+
+func TestIteratingNonIterable(t *testing.T) {
+	program := parse("for (x of 5) { x }")
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(comp.ByteCode())
+	err = vm.Run()
+	if err == nil || err.Error() != "INTEGER is not iterable" {
+		t.Fatalf("wrong VM error: got=%v", err)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 5; x", 5},
+		{"let x = 1; x += 2; x -= 1; x *= 10; x /= 4; x", 5},
+		{"let x = 1; let y = x = 7; y + x", 14},
+		{"let x = 1; [x++, x, ++x, x--, --x]", []int{1, 2, 3, 3, 1}},
+		{"let a = [1, 2, 3]; a[1] = 20; a[2] += 5; a", []int{1, 20, 8}},
+		{"let a = [5]; [a[0]++, a[0], --a[0]]", []int{5, 6, 5}},
+		{`let h = {"a": 1}; h["a"] += 1; h["b"] = 5; h["a"] + h["b"]`, 7},
+		{"let x = 1; let f = fn() { x = 10; }; f(); x", 10},
+		{"let f = fn() { let n = 1; n += 2; n }; f()", 3},
+		{"let counter = fn() { let c = 0; fn() { c++; c } }; let next = counter(); next(); next(); next()", 3},
+		{"let f = fn() { let n = 0; let inc = fn() { n += 1 }; inc(); inc(); n }; f()", 2},
+		{"let f = fn(n) { let get = fn() { n }; n = 7; get() }; f(1)", 7},
+		{"let f = fn() { let v = 1; fn() { fn() { v *= 3; v } } }; let g = f()(); g(); g()", 9},
+		// Locals of an earlier call must not share the cell of the closure captured before
+		{"let f = fn() { let v = 0; let g = fn() { v }; v = 1; g }; let a = f(); let b = f(); a() + b()", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a = [1]; a[5] = 1;", "index out of range: 5"},
+		{"let a = [1]; a[\"x\"] = 1;", "index operator has wrong type that is not supported yet ARRAY"},
+		{"let h = {}; h[[1]] = 1;", "unusable as hash key: ARRAY"},
+		{"let s = \"a\"; s++;", "type mismatch: STRING + INTEGER"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q but resulted in none.", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Fatalf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []vmTestCase{
+		{"const a = 1; a + 1", 2},
+		{"let a = 1; if (true) { let a = 2; a = 3; }; a", 1},
+		{"let a = 1; if (true) { let b = 2; a = a + b; }; a", 3},
+		{"let f = fn() { let x = 1; if (true) { let x = 2; x++; }; x }; f()", 1},
+		{"let x = 0; for (let x = 5; x < 6; x++) { }; x", 0},
+		{"let fns = []; for (x of [1, 2, 3]) { fns = push(fns, fn() { x }) }; fns[0]() + fns[2]()", 4},
+		{"let fns = []; for (let i = 0; i < 3; i++) { let j = i; fns = push(fns, fn() { j }) }; fns[0]() + fns[1]()", 1},
+		{"let fns = []; for (let i = 0; i < 3; i++) { fns = push(fns, fn() { i }) }; fns[0]() + fns[2]()", 2},
+		{"let f = fn() { let fns = []; for (let i = 0; i < 3; i++) { fns = push(fns, fn() { i }) }; fns }; f()[1]()", 1},
+		{"let f = fn() { let fns = []; for (x of [1, 2]) { fns = push(fns, fn() { x }) }; fns }; let fns = f(); fns[0]() + fns[1]()", 3},
+		{"let g = fn() { let r = 0; let i = 0; while (i < 3) { let v = i; let add = fn() { r += v }; add(); i++ }; r }; g()", 3},
+		{"let k = 0; if (true) { let n = 1; let inc = fn() { n++ }; inc(); k = n }; k", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`let o = {"name": "bjs"}; o.name`, "bjs"},
+		{`let o = {"name": "bjs"}; o.missing`, Null},
+		{`let o = {"n": 1}; o.n = 5; o.n += 2; o.n++; o["n"]`, 8},
+		{`let o = {}; o.inner = {"x": 1}; o.inner.x`, 1},
+		{`let o = {"add": fn(a, b) { a + b }}; o.add(1, 2)`, 3},
+		{`"hello".length`, 5},
+		{`"Hello".toUpperCase() + "Hello".toLowerCase()`, "HELLOhello"},
+		{`"  a ".trim()`, "a"},
+		{`"a,b,c".split(",")[1]`, "b"},
+		{`"hello".indexOf("l")`, 2},
+		{`[1, 2, 3].length`, 3},
+		{`let a = [1]; a.push(2, 3)`, 3},
+		{`let a = [1]; let b = a; a.push(2); b`, []int{1, 2}},
+		{`let a = [1, 2]; a.pop() + a.length`, 3},
+		{`[].pop()`, Null},
+		{`[1, 2, 3].join("-")`, "1-2-3"},
+		{`[1, 2, 3].join()`, "1,2,3"},
+		{`["a", "b"].indexOf("b") + [1].indexOf(5)`, 0},
+		{`[1, 2, 3, 4].slice(1, -1)`, []int{2, 3}},
+		{`[1, 2, 3].slice(5)`, []int{}},
+		{`let a = [1]; let push = a.push; push(4); a`, []int{1, 4}},
+		{`let f = fn(arr) { arr.push(arr.length) }; let a = []; f(a); f(a); a`, []int{0, 1}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []vmTestCase{
+		{"function add(a, b) { a + b } add(1, 2)", 3},
+		{"let r = twice(3); function twice(x) { x * 2 } r", 6},
+		{"function even(n) { if (n == 0) { return true } odd(n - 1) } function odd(n) { if (n == 0) { return false } even(n - 1) } even(10)", true},
+		{"function fact(n) { if (n < 2) { return 1 } n * fact(n - 1) } fact(5)", 120},
+		{`let f = fn() { let r = ""; try { get() } catch (e) { r = e.kind + ": " + e.message } function get() { x } let x = 1; r }; f()`, "ReferenceError: identifier not found: x"},
+		{`let r = ""; function set() { c = 2 } try { set() } catch (e) { r = e.kind + ": " + e.message } const c = 1; r`, "ReferenceError: assignment to undeclared identifier: c"},
+		{`let r = ""; function set() { c++ } const c = 1; try { set() } catch (e) { r = e.kind + ": " + e.message } r`, "TypeError: assignment to constant variable: c"},
+		{"let f = fn() { function get() { x } let x = 1; x++; get() }; f()", 2},
+		{"let fns = []; for (x of [1, 2, 3]) { let v = x * 10; function get() { v } fns.push(get) }; fns[0]() + fns[2]()", 40},
+		{"if (true) { function inner() { 5 } inner() }", 5},
+		{"let anon = function(a) { a * 2 }; anon(4)", 8},
+		{"let add = (a, b) => a + b; add(2, 3)", 5},
+		{"let seven = () => { 7 }; seven()", 7},
+		{"let adder = x => y => x + y; adder(1)(2)", 3},
+		{"[1, 2, 3].slice(1).length", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let r = 0; try { throw 5 } catch (e) { r = e }; r", 5},
+		{`let r = ""; try { 1 + "a" } catch (e) { r = e.kind + ": " + e.message }; r`, "TypeError: type mismatch: INTEGER + STRING"},
+		{`let r = ""; try { missing } catch (e) { r = e.kind }; r`, "ReferenceError"},
+		{`let r = ""; try { [1].size } catch (e) { r = e.kind }; r`, "TypeError"},
+		{`let r = ""; try { throw error("bad", "ValueError") } catch (e) { r = e.kind + e.message }; r`, "ValueErrorbad"},
+		{"let r = 0; try { 1() } catch { r = 1 } finally { r = r + 10 }; r", 11},
+		{"let r = 0; try { r = 1 } finally { r = r * 5 }; r", 5},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let r = []; let f = fn() { try { return 1 } finally { r.push(2) } }; f() + r[0]", 3},
+		{"let r = 0; let f = fn(x) { if (x == 0) { throw 7 } f(x - 1) }; try { f(3) } catch (e) { r = e }; r", 7},
+		{"let r = 0; try { try { throw 1 } finally { r = 10 } } catch (e) { r = r + e }; r", 11},
+		{"let r = 0; try { try { throw 1 } catch (e) { throw e + 1 } } catch (e) { r = e }; r", 2},
+		{"let r = 0; for (x of [1, 2, 3]) { try { if (x == 2) { continue } r += x } finally { r += 10 } }; r", 34},
+		{"let r = 0; while (true) { try { break } finally { r = 1 } }; r", 1},
+		{"let r = 0; for (x of [1, 2]) { try { throw x } catch (e) { r += e } }; r", 3},
+		{"let e = 1; try { throw 2 } catch (e) { e }; e", 1},
+		{"let r = []; for (x of [1, 2]) { r.push([x, if (x == 2) { try { break } finally { r.push(9) } } else { x }]) }; r.length", 2},
+		{"let r = 0; let f = fn() { let a = 1; let b = [a, 2]; try { [b[0], b[1], 1 + true] } catch (e) { r = a + b[1] } }; f(); r", 3},
+	}
+	runVmTests(t, tests)
+}
+
+func TestLateGlobals(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fn() { g() }; let g = fn() { 42 }; f()", 42},
+		{"let f = fn() { fn() { n } }; let n = 3; f()()", 3},
+		{`if (false) { missing }; "ok"`, "ok"},
+	}
+	runVmTests(t, tests)
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"foobar", "identifier not found: foobar"},
+		{"let f = fn() { g }; f(); let g = 1;", "identifier not found: g"},
+		{"if (true) { let a = 1; }; a", "identifier not found: a"},
+	}
+	for _, tt := range errors {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		err := New(comp.ByteCode()).Run()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong VM error for %q: want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestUncaughtExceptions(t *testing.T) {
+	tests := []vmTestCase{
+		{`throw "oops"`, "uncaught exception: oops"},
+		{`throw error("bad")`, "bad"},
+		{"try { 1 + true } catch (e) { throw e }", "type mismatch: INTEGER + BOOLEAN"},
+		{"try { throw 1 } finally { 2 }", "uncaught exception: 1"},
+		{"try { 1 } finally { throw 2 }", "uncaught exception: 2"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.ByteCode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected VM error for %q but resulted in none.", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Fatalf("wrong VM error: want=%q, got=%q", tt.expected, err)
+		}
+	}
+}
+
+func TestRuntimeErrorStack(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + true", "    at 1:3"},
+		{"let f = fn() { 1 + true };\nf()", "    at f (1:18)\n    at 2:2"},
+		{"function outer() { [1].map(fn() { -true }) }\nouter()", "    at outer (1:23)\n    at 2:6"},
+		{"let g = fn(h) { h() };\ng(fn() { len(1) })", "    at <anonymous> (2:13)\n    at g (1:18)\n    at 2:2"},
+		{"let f = fn() { throw 1 };\ntry { f() } catch (e) { 1 };\nlet g = fn() { f() };\ng()", "    at f (1:16)\n    at g (3:17)\n    at 4:2"},
+	}
+	for _, tt := range tests {
+		program := parse(tt.input)
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		err := New(comp.ByteCode()).Run()
+		runtimeErr, ok := err.(*RuntimeError)
+		if !ok {
+			t.Fatalf("no runtime error for %q. got=%T (%v)", tt.input, err, err)
+		}
+		if stack := diagnostic.Trace(runtimeErr.Stack); stack != tt.expected {
+			t.Errorf("wrong stack for %q.\nwant=%q\ngot =%q", tt.input, tt.expected, stack)
+		}
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	defer func(policy object.OverflowPolicy) { object.Overflow = policy }(object.Overflow)
+	input := "let max = 9223372036854775807; [max + 1, -(-max - 1), max * 3 / 3 > max]"
+	expected := map[object.OverflowPolicy]string{
+		object.OverflowWrap:    "[-9223372036854775808, -9223372036854775808, false]",
+		object.OverflowError:   "integer overflow: 9223372036854775807 + 1",
+		object.OverflowPromote: "[9223372036854775808n, 9223372036854775808n, false]",
+	}
+	for policy, want := range expected {
+		object.Overflow = policy
+		if got := runForResult(t, input); got != want {
+			t.Errorf("wrong result with %s policy. want=%s, got=%s", policy, want, got)
+		}
+	}
+}
+
+func TestBigIntArithmetic(t *testing.T) {
+	defer func(policy object.OverflowPolicy) { object.Overflow = policy }(object.Overflow)
+	object.Overflow = object.OverflowPromote
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let big = 9223372036854775807 + 1; big - 1", "9223372036854775807n"},
+		{"let big = 9223372036854775807 + 1; big * big", "85070591730234615865843651857942052864n"},
+		{"let big = 9223372036854775807 + 1; -big / 2", "-4611686018427387904n"},
+		{"let big = 9223372036854775807 + 1; [big == big + 0, big != 1, big < 1, big >= big]", "[true, true, false, true]"},
+		{"let big = 9223372036854775807 + 1; big / 0", "division by zero"},
+		{"let big = 9223372036854775807 + 1; big + 1.5", "type mismatch: BIGINT + FLOAT"},
+	}
+	for _, tt := range tests {
+		if got := runForResult(t, tt.input); got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestBigIntLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"18446744073709551616n * 18446744073709551616n", "340282366920938463463374607431768211456n"},
+		{"-10n / 3n + 1", "-2n"},
+		{"[2n > 1, 1n == 1, 3n <= 2n, 5n != 5n]", "[true, true, false, false]"},
+		{`let h = {10n: "big", 10: "small"}; [h[10n], h[10]]`, "[big, small]"},
+		{`[bigint(7), bigint("-12345678901234567890"), bigint(3.0), int(42n), int(2.9), int("17")]`, "[7n, -12345678901234567890n, 3n, 42, 2, 17]"},
+		{"int(18446744073709551616n)", "cannot convert 18446744073709551616n to an integer"},
+		{"bigint(0.5)", "cannot convert 0.5 to a big integer"},
+		{"bigint(true)", "argument to bigint is invalid. got=BOOLEAN"},
+		{"1n + 0.5", "type mismatch: BIGINT + FLOAT"},
+		{"1n / 0n", "division by zero"},
+	}
+	for _, tt := range tests {
+		if got := runForResult(t, tt.input); got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	defer func(policy object.OverflowPolicy) { object.Overflow = policy }(object.Overflow)
+	object.Overflow = object.OverflowError
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[7 % 3, -7 % 3, 7.5 % 2, 2 ** 10, 2 ** 3 ** 2, -2 ** 2, 2 ** -1, 4 ** 0.5]", "[1, -1, 1.5, 1024, 512, -4, 0.5, 2]"},
+		{"[6 & 3, 6 | 3, 6 ^ 3, ~5, 1 << 4, -16 >> 2, -1 >>> 60, 1 << 64]", "[2, 7, 5, -6, 16, -4, 15, 1]"},
+		{"[1 + 2 << 1, 1 | 2 & 3, 2 * 3 ** 2]", "[6, 3, 18]"},
+		{"[2n ** 100n, 7n % -3n, ~5n, 1n << 70n, -5n >> 1n, 6n | 1, 1n << -1n]", "[1267650600228229401496703205376n, 1n, -6n, 1180591620717411303424n, -3n, 7n, 0n]"},
+		{"[(-2) ** 63, 1 << 63]", "[-9223372036854775808, -9223372036854775808]"},
+		{"3 ** 40", "integer overflow: 3 ** 40"},
+		{"7 % 0", "division by zero"},
+		{"2n ** -1n", "negative exponent: 2n ** -1n"},
+		{"1n >>> 1n", "unknown operator: BIGINT >>> BIGINT"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"~1.5", "unknown operator: ~FLOAT"},
+	}
+	for _, tt := range tests {
+		if got := runForResult(t, tt.input); got != tt.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// Runs the input and returns back the inspected result or the message of the error that stopped it
+func runForResult(t *testing.T, input string) string {
+	t.Helper()
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(comp.ByteCode())
+	if err := vm.Run(); err != nil {
+		return err.Error()
+	}
+	return vm.LastPoppedStackElem().Inspect()
+}